@@ -0,0 +1,258 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrUnauthenticated is returned by an [Authenticator] when the credentials it checks are missing.
+var ErrUnauthenticated = errors.New("jsonrpc2: unauthenticated")
+
+// Credentials holds what a client presented to authenticate.
+type Credentials struct {
+	Header http.Header     // The headers of the HTTP request or WebSocket opening handshake. Nil for stream and stdio connections.
+	Peer   *Peer           // The remote end of the connection, including its TLS state. Nil for stdio.
+	Params json.RawMessage // The params of the login request, when authenticating with the method set by [WithLoginMethod].
+}
+
+// Principal is the authenticated identity requests are handled on behalf of.
+type Principal struct {
+	Name       string         // The name identifying the principal, e.g. a user name or the subject of a certificate.
+	Roles      []string       // The roles granted to the principal, as checked by [MethodRoles].
+	Attributes map[string]any // Additional information about the principal, e.g. token claims.
+}
+
+// HasRole reports whether the principal has been granted role.
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+// Authenticator checks the credentials of a client before its requests are dispatched.
+type Authenticator interface {
+	// Authenticate returns the principal identified by cred, or an error if cred is missing or invalid.
+	Authenticate(ctx context.Context, cred *Credentials) (*Principal, error)
+}
+
+// AuthenticatorFunc is an adapter to use an ordinary function as an [Authenticator].
+type AuthenticatorFunc func(ctx context.Context, cred *Credentials) (*Principal, error)
+
+// Authenticate implements [Authenticator].
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, cred *Credentials) (*Principal, error) {
+	return f(ctx, cred)
+}
+
+// BearerTokenAuthenticator returns an [Authenticator] that passes the bearer token of the Authorization header to verify.
+func BearerTokenAuthenticator(verify func(ctx context.Context, token string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, cred *Credentials) (*Principal, error) {
+		scheme, token, ok := strings.Cut(cred.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, ErrUnauthenticated
+		}
+		return verify(ctx, token)
+	})
+}
+
+// APIKeyAuthenticator returns an [Authenticator] that passes the API key sent in the given header, e.g. "X-API-Key", to verify.
+func APIKeyAuthenticator(header string, verify func(ctx context.Context, key string) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, cred *Credentials) (*Principal, error) {
+		key := cred.Header.Get(header)
+		if key == "" {
+			return nil, ErrUnauthenticated
+		}
+		return verify(ctx, key)
+	})
+}
+
+// ClientCertAuthenticator returns an [Authenticator] that identifies clients by the certificate chain verified during a mutual TLS handshake,
+// see [WithTLSConfig]. If verify is nil, the principal is named after the common name of the client certificate.
+func ClientCertAuthenticator(verify func(ctx context.Context, chain []*x509.Certificate) (*Principal, error)) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, cred *Credentials) (*Principal, error) {
+		if cred.Peer == nil || cred.Peer.TLS == nil || len(cred.Peer.TLS.VerifiedChains) == 0 {
+			return nil, ErrUnauthenticated
+		}
+		chain := cred.Peer.TLS.VerifiedChains[0]
+		if verify == nil {
+			return &Principal{Name: chain[0].Subject.CommonName}, nil
+		}
+		return verify(ctx, chain)
+	})
+}
+
+// FirstAuthenticator returns an [Authenticator] that tries each of auths in order and returns the first principal found.
+// If all of them fail, the error of the first one that did not return [ErrUnauthenticated] is returned.
+func FirstAuthenticator(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(ctx context.Context, cred *Credentials) (*Principal, error) {
+		errResult := ErrUnauthenticated
+		for _, auth := range auths {
+			p, err := auth.Authenticate(ctx, cred)
+			if err == nil {
+				return p, nil
+			}
+			if errResult == ErrUnauthenticated {
+				errResult = err
+			}
+		}
+		return nil, errResult
+	})
+}
+
+// Authorizer reports whether principal may call the method of req. The principal is nil if no [Authenticator] is set.
+type Authorizer func(ctx context.Context, principal *Principal, req *Request) bool
+
+// MethodRoles returns an [Authorizer] that allows a method to principals having any of the roles listed for it in rules.
+// Methods missing from rules are allowed to all principals.
+func MethodRoles(rules map[string][]string) Authorizer {
+	return func(ctx context.Context, principal *Principal, req *Request) bool {
+		roles, ok := rules[req.Method]
+		if !ok {
+			return true
+		}
+		return slices.ContainsFunc(roles, principal.HasRole)
+	}
+}
+
+// WithAuthenticator makes a server authenticate clients with auth before dispatching their requests.
+// HTTP requests and WebSocket opening handshakes are authenticated from their headers and rejected with 401 Unauthorized.
+// Stream connections are authenticated once when established; if that fails, requests are rejected with the error
+// set by [WithAuthError] until the client authenticates with the method set by [WithLoginMethod].
+// Handlers can retrieve the authenticated principal with [PrincipalFromContext].
+func WithAuthenticator(auth Authenticator) ServerOption {
+	return func(o *serverOptions) {
+		o.authenticator = auth
+	}
+}
+
+// WithLoginMethod sets the method clients of persistent connections call to authenticate, passing their credentials as params.
+// The params are given to the [Authenticator] as [Credentials.Params], and the call returns true once the connection is authenticated.
+// It has no effect on HTTP requests, which are authenticated individually.
+func WithLoginMethod(method string) ServerOption {
+	return func(o *serverOptions) {
+		o.loginMethod = method
+	}
+}
+
+// WithAuthError sets the error unauthenticated requests are rejected with. It defaults to an [Unauthorized] error.
+func WithAuthError(err Error) ServerOption {
+	return func(o *serverOptions) {
+		o.authError = &err
+	}
+}
+
+// WithAuthorizer makes a server check every request with authorize after authentication.
+// Requests that are not allowed are rejected with a [Forbidden] error.
+func WithAuthorizer(authorize Authorizer) ServerOption {
+	return func(o *serverOptions) {
+		o.authorizer = authorize
+	}
+}
+
+// session holds the state of a connection, or of a single HTTP request.
+type session struct {
+	bucket *tokenBucket // The rate limiter of the connection, if any.
+
+	mu        sync.Mutex
+	principal *Principal
+}
+
+// sessionKey is the context key for the [session] of the current connection.
+type sessionKey struct{}
+
+// PrincipalFromContext returns the principal a request is handled on behalf of, as authenticated by the [Authenticator] of the server.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return nil, false
+	}
+	p := s.get()
+	return p, p != nil
+}
+
+// get returns the authenticated principal, or nil.
+func (s *session) get() *Principal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.principal
+}
+
+// set records the authenticated principal.
+func (s *session) set(p *Principal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.principal = p
+}
+
+// startSession starts the session of a connection or HTTP request presenting cred, and returns a copy of ctx carrying it.
+// The returned error reports why the client could not be authenticated; it is nil if no [Authenticator] is set.
+func (d *dispatcher) startSession(ctx context.Context, cred *Credentials) (context.Context, error) {
+	s := &session{bucket: d.limiter.newConnBucket()}
+	ctx = context.WithValue(ctx, sessionKey{}, s)
+	if d.authenticator == nil {
+		return ctx, nil
+	}
+	p, err := d.authenticator.Authenticate(ctx, cred)
+	if err != nil {
+		return ctx, err
+	}
+	if p == nil {
+		p = &Principal{}
+	}
+	s.set(p)
+	return ctx, nil
+}
+
+// loginSession returns the session req logs in, if req is a call of the login method, see [WithLoginMethod].
+func (d *dispatcher) loginSession(ctx context.Context, req *Request) (*session, bool) {
+	if d.authenticator == nil || d.loginMethod == "" || req.Method != d.loginMethod {
+		return nil, false
+	}
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return s, ok
+}
+
+// authorize checks req against the authentication and authorization settings of the server.
+// It returns the response rejecting req, or nil if req may be dispatched.
+func (d *dispatcher) authorize(ctx context.Context, req *Request) *Response {
+	var principal *Principal
+	if d.authenticator != nil {
+		if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+			principal = s.get()
+		}
+		if principal == nil {
+			return NewResponse(req.ID, WithError(d.authErr()))
+		}
+	}
+
+	if d.authorizer != nil && !d.authorizer(ctx, principal, req) {
+		return newErrorResponse(req.ID, Forbidden, "Forbidden")
+	}
+	return nil
+}
+
+// login authenticates the session with the params of req.
+func (d *dispatcher) login(ctx context.Context, s *session, req *Request) *Response {
+	cred := &Credentials{Params: req.Params}
+	cred.Peer, _ = PeerFromContext(ctx)
+	p, err := d.authenticator.Authenticate(ctx, cred)
+	if err != nil {
+		return NewResponse(req.ID, WithError(d.authErr()))
+	}
+	if p == nil {
+		p = &Principal{}
+	}
+	s.set(p)
+	return NewResponse(req.ID, WithResult(true))
+}
+
+// authErr returns the error unauthenticated requests are rejected with.
+func (d *dispatcher) authErr() Error {
+	if d.authError != nil {
+		return *d.authError
+	}
+	return *NewError(Unauthorized, "Unauthorized")
+}
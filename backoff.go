@@ -0,0 +1,68 @@
+package jsonrpc2
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff describes an exponential backoff schedule.
+// The zero value is usable and behaves like [DefaultBackoff].
+type Backoff struct {
+	Initial    time.Duration // The delay before the first retry. Defaults to 100ms.
+	Max        time.Duration // The upper bound of a single delay. Defaults to 10s.
+	Multiplier float64       // The factor applied to the delay after each attempt. Defaults to 2.
+	Jitter     float64       // The fraction of the delay that is randomized, between 0 and 1.
+}
+
+// DefaultBackoff is the backoff schedule used when none is configured.
+var DefaultBackoff = Backoff{
+	Initial:    100 * time.Millisecond,
+	Max:        10 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay to wait before the given retry attempt.
+// The first retry is attempt 1.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(b.Initial)
+	for i := 1; i < attempt && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	delay = min(delay, float64(b.Max))
+
+	if b.Jitter > 0 {
+		jitter := min(b.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
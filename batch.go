@@ -0,0 +1,229 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	// ErrMissingResponse is the error of a call of a [Batch] the server did not answer.
+	ErrMissingResponse = errors.New("jsonrpc2: no response to request in batch")
+	// ErrDuplicateResponse is the error of a call of a [Batch] the server answered more than once.
+	ErrDuplicateResponse = errors.New("jsonrpc2: several responses to request in batch")
+)
+
+// Batch builds a batch of calls and notifications sent at once, e.g.
+//
+//	b := jsonrpc2.NewBatch()
+//	sum := b.Call("sum", []int{1, 2})
+//	b.Notify("log", "summing")
+//	if _, err := b.Send(ctx, client); err != nil {
+//		...
+//	}
+//	var n int
+//	err := sum.Decode(&n)
+//
+// Replies are matched to calls by ID, whatever their order. A Batch is sent once and is not safe for concurrent use.
+type Batch struct {
+	reqs   []*Request
+	calls  []*Call
+	ids    map[string]bool
+	nextID int
+	err    error
+	sent   bool
+}
+
+// NewBatch creates an empty [Batch].
+func NewBatch() *Batch {
+	return &Batch{ids: make(map[string]bool)}
+}
+
+// Call adds a call of method with params to the batch and returns it, completed by [Batch.Send].
+// The call is given an integer ID unique within the batch.
+func (b *Batch) Call(method string, params any) *Call {
+	req, err := NewRequest(method, WithParams(params), WithID(b.newID()))
+	if err != nil {
+		return b.fail(&Request{JSONRPC: version, Method: method}, err)
+	}
+	return b.Add(req)
+}
+
+// Notify adds a notification of method with params to the batch.
+func (b *Batch) Notify(method string, params any) {
+	req, err := NewRequest(method, WithParams(params))
+	if err != nil {
+		b.fail(&Request{JSONRPC: version, Method: method}, err)
+		return
+	}
+	b.Add(req)
+}
+
+// Add adds req, e.g. created by [NewRequest] with [WithPositionalParams], to the batch.
+// The ID of req must be unique within the batch. It returns the call of req, completed by [Batch.Send],
+// or nil if req is a notification.
+func (b *Batch) Add(req *Request) *Call {
+	if req.IsNotification() {
+		b.reqs = append(b.reqs, req)
+		return nil
+	}
+	key, err := idKey(req.ID)
+	if err == nil && b.ids[key] {
+		err = fmt.Errorf("duplicate request id %s", key)
+	}
+	if err != nil {
+		return b.fail(req, err)
+	}
+	b.ids[key] = true
+	b.reqs = append(b.reqs, req)
+	call := newCall(req)
+	b.calls = append(b.calls, call)
+	return call
+}
+
+// newID returns an integer ID not used by the calls added so far.
+func (b *Batch) newID() int {
+	for {
+		b.nextID++
+		if !b.ids[strconv.Itoa(b.nextID)] {
+			return b.nextID
+		}
+	}
+}
+
+// fail records that req could not be added to the batch and returns its failed call. The batch will not be sent.
+func (b *Batch) fail(req *Request, err error) *Call {
+	err = fmt.Errorf("failed to add %s to batch: %w", req.Method, err)
+	b.err = errors.Join(b.err, err)
+	call := newCall(req)
+	call.complete(nil, err)
+	return call
+}
+
+// Send sends the batch with c and completes its calls. It returns the responses to the calls in the order they were added,
+// with nil for the calls left unanswered.
+//
+// A response to the whole batch carrying an error, e.g. a parse error, is returned as an [Error] and fails all calls.
+// Calls the server did not answer fail with [ErrMissingResponse] and calls answered more than once with [ErrDuplicateResponse];
+// these errors and responses matching no call are joined in the returned error.
+func (b *Batch) Send(ctx context.Context, c Client) ([]*Response, error) {
+	if b.sent {
+		return nil, errors.New("batch already sent")
+	}
+	b.sent = true
+
+	err := b.err
+	if err == nil && len(b.calls) == 0 {
+		err = ErrMissingID
+	}
+	var replies []*Response
+	if err == nil {
+		replies, err = CallBatchResponses(ctx, c, b.reqs)
+	}
+	if err != nil {
+		for _, call := range b.calls {
+			if call.err == nil {
+				call.complete(nil, err)
+			}
+		}
+		return nil, err
+	}
+
+	index := make(map[string]int, len(b.calls))
+	for i, call := range b.calls {
+		key, _ := idKey(call.Request.ID)
+		index[key] = i
+	}
+	resps := make([]*Response, len(b.calls))
+	var errs []error
+	duplicates := make(map[int]bool)
+	for _, resp := range replies {
+		key, _ := idKey(resp.ID)
+		i, ok := index[key]
+		switch {
+		case !ok && resp.Error != nil:
+			errs = append(errs, resp.Error)
+		case !ok:
+			errs = append(errs, fmt.Errorf("unexpected response with id %s", key))
+		case resps[i] != nil:
+			duplicates[i] = true
+		default:
+			resps[i] = resp
+		}
+	}
+
+	for i, call := range b.calls {
+		key, _ := idKey(call.Request.ID)
+		switch {
+		case duplicates[i]:
+			err := fmt.Errorf("request %s: %w", key, ErrDuplicateResponse)
+			errs = append(errs, err)
+			call.complete(nil, err)
+		case resps[i] == nil:
+			err := fmt.Errorf("request %s: %w", key, ErrMissingResponse)
+			errs = append(errs, err)
+			call.complete(nil, err)
+		default:
+			call.complete(resps[i], nil)
+		}
+	}
+	return resps, errors.Join(errs...)
+}
+
+// BatchCaller is implemented by the clients of this package to return the replies to a batch as responses,
+// decoded as set by [WithUseNumber] and [WithRawResults]. Clients wrapping other clients can implement it
+// with [CallBatchResponses] so that a [Batch] sent through them keeps the decoding of the client they wrap.
+type BatchCaller interface {
+	// CallBatchResponses sends a batch as CallBatch does and returns the replies, or nil for a batch of notifications.
+	// A reply to the whole batch carrying an error is returned as an [Error].
+	CallBatchResponses(ctx context.Context, reqs []*Request) ([]*Response, error)
+}
+
+// CallBatchResponses sends reqs with c and returns the replies as responses, with the CallBatchResponses method of c
+// if it is a [BatchCaller]. Otherwise, the replies are decoded again from the result of CallBatch, numbers becoming float64.
+func CallBatchResponses(ctx context.Context, c Client, reqs []*Request) ([]*Response, error) {
+	if bc, ok := c.(BatchCaller); ok {
+		return bc.CallBatchResponses(ctx, reqs)
+	}
+	reply, err := c.CallBatch(ctx, reqs)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch response: %w", err)
+	}
+	return decodeResponses(data, decodeAny)
+}
+
+// decodeResponses decodes the replies to a batch, decoding results according to mode.
+// A single response carrying an error is returned as an [Error].
+func decodeResponses(data []byte, mode resultDecoding) ([]*Response, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		resp, err := decodeResponse(trimmed, mode)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error == nil {
+			return nil, errors.New("unexpected single response to batch")
+		}
+		return nil, resp.Error
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	}
+	resps := make([]*Response, len(raws))
+	for i, raw := range raws {
+		resp, err := decodeResponse(raw, mode)
+		if err != nil {
+			return nil, err
+		}
+		resps[i] = resp
+	}
+	return resps, nil
+}
@@ -0,0 +1,72 @@
+package jsonrpc2
+
+import "context"
+
+// Call is a pending call, completed once its response is received or the call fails.
+// Calls are made asynchronously by the Go methods of clients and by [Batch], e.g.
+//
+//	calls := make([]*jsonrpc2.Call, len(reqs))
+//	for i, req := range reqs {
+//		calls[i] = client.Go(ctx, req)
+//	}
+//	for _, call := range calls {
+//		resp, err := call.Wait()
+//		...
+//	}
+type Call struct {
+	Request *Request // The request sent.
+
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// newCall returns a pending call of req.
+func newCall(req *Request) *Call {
+	return &Call{
+		Request: req,
+		done:    make(chan struct{}),
+	}
+}
+
+// Done returns a channel closed once the call is complete.
+func (c *Call) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait waits for the call to complete and returns its response, or the error that made it fail.
+// A response carrying an error is returned as is; use [Response.Err] to check it.
+func (c *Call) Wait() (*Response, error) {
+	<-c.done
+	return c.resp, c.err
+}
+
+// Decode waits for the call to complete and decodes its result into the value pointed to by v, see [Response.DecodeResult].
+func (c *Call) Decode(v any) error {
+	resp, err := c.Wait()
+	if err != nil {
+		return err
+	}
+	return resp.DecodeResult(v)
+}
+
+// complete completes the call with resp or err. It must be called once.
+func (c *Call) complete(resp *Response, err error) {
+	c.resp, c.err = resp, err
+	close(c.done)
+}
+
+// Go makes the call of req with call, e.g. the Call method of a [Client], in a new goroutine and returns it.
+// Like the Go methods of the clients of this package, it fails a notification with [ErrMissingID].
+// It lets clients wrapping other clients provide a Go method.
+func Go(ctx context.Context, req *Request, call func(context.Context, *Request) (*Response, error)) *Call {
+	c := newCall(req)
+	if req.IsNotification() {
+		c.complete(nil, ErrMissingID)
+		return c
+	}
+	go func() {
+		c.complete(call(ctx, req))
+	}()
+	return c
+}
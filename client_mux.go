@@ -0,0 +1,335 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NotificationHandler is a function type that processes a request sent by the server to the client,
+// such as a notification pushed over a persistent connection.
+type NotificationHandler func(ctx context.Context, req *Request)
+
+// WithNotificationHandler sets the handler a [WebSocketClient] or [HTTPClient] calls for requests sent by the server.
+// The handler is called from the connection's read loop, in the order the requests arrive,
+// so it must not block for long. An [HTTPClient] calls it for the requests streamed before a response, see [WithEventStream].
+func WithNotificationHandler(handler NotificationHandler) ClientOption {
+	return func(o *clientOptions) {
+		o.notificationHandler = handler
+	}
+}
+
+// errNotSent is wrapped by the errors of [messageConn] writes that gave up before sending anything.
+var errNotSent = errors.New("message not sent")
+
+// messageConn is a connection that carries whole JSON-RPC messages.
+type messageConn interface {
+	readMessage() ([]byte, error)
+	writeMessage(ctx context.Context, data []byte) error
+	Close() error
+}
+
+// clientMux multiplexes concurrent calls over a single [messageConn], matching responses to calls by ID.
+// It is used by [WebSocketClient] and, for each of its connections, by [TCPClient].
+type clientMux struct {
+	conn     messageConn
+	onNotify NotificationHandler
+
+	mu      sync.Mutex
+	pending map[string]*pendingCall
+	err     error // The reason the connection stopped, set before done is closed.
+	done    chan struct{}
+}
+
+// pendingCall is a call or batch waiting for its response.
+type pendingCall struct {
+	keys  []string    // The ID keys of the requests, see idKey.
+	reply chan []byte // Receives the raw response. It is buffered so that delivery never blocks.
+}
+
+// newClientMux creates a new [clientMux] and starts reading from conn.
+func newClientMux(conn messageConn, onNotify NotificationHandler) *clientMux {
+	m := &clientMux{
+		conn:     conn,
+		onNotify: onNotify,
+		pending:  make(map[string]*pendingCall),
+		done:     make(chan struct{}),
+	}
+	go m.readLoop()
+	return m
+}
+
+// idKey returns the key identifying a request ID.
+func idKey(id any) (string, error) {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal id: %w", err)
+	}
+	return string(data), nil
+}
+
+// batchKeys returns the ID keys of the requests of a batch that are not notifications.
+// It returns no keys if all requests are notifications, as no reply is expected then.
+func batchKeys(reqs []*Request) ([]string, error) {
+	var keys []string
+	for _, req := range reqs {
+		if req.IsNotification() {
+			continue
+		}
+		key, err := idKey(req.ID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// call sends data, the encoding of the requests with the given ID keys, and waits for the response.
+func (m *clientMux) call(ctx context.Context, data []byte, keys []string) ([]byte, error) {
+	p := &pendingCall{
+		keys:  keys,
+		reply: make(chan []byte, 1),
+	}
+
+	m.mu.Lock()
+	if m.err != nil {
+		m.mu.Unlock()
+		return nil, m.err
+	}
+	for i, key := range keys {
+		if _, exists := m.pending[key]; exists {
+			for _, k := range keys[:i] {
+				delete(m.pending, k)
+			}
+			m.mu.Unlock()
+			return nil, fmt.Errorf("a request with id %s is already in flight", key)
+		}
+		m.pending[key] = p
+	}
+	m.mu.Unlock()
+
+	if err := m.write(ctx, data); err != nil {
+		m.remove(p)
+		return nil, err
+	}
+
+	select {
+	case reply := <-p.reply:
+		return reply, nil
+	case <-ctx.Done():
+		m.remove(p)
+		return nil, ctx.Err()
+	case <-m.done:
+		select {
+		case reply := <-p.reply:
+			return reply, nil
+		default:
+			return nil, m.err
+		}
+	}
+}
+
+// notify sends data without waiting for a response.
+func (m *clientMux) notify(ctx context.Context, data []byte) error {
+	select {
+	case <-m.done:
+		return m.err
+	default:
+	}
+	return m.write(ctx, data)
+}
+
+// write sends data. As a message may have been partially sent, the connection is closed if the write fails,
+// failing the pending calls with [ErrConnectionLost], unless the message was not sent at all.
+func (m *clientMux) write(ctx context.Context, data []byte) error {
+	err := m.conn.writeMessage(ctx, data)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errNotSent):
+		return fmt.Errorf("failed to send request: %w", err)
+	case ctx.Err() != nil:
+		err = fmt.Errorf("failed to send request: %w", ctx.Err())
+	default:
+		err = fmt.Errorf("%w: failed to send request: %w", ErrConnectionLost, err)
+	}
+	m.stop(err)
+	m.conn.Close()
+	return err
+}
+
+// remove forgets a pending call.
+func (m *clientMux) remove(p *pendingCall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range p.keys {
+		if m.pending[key] == p {
+			delete(m.pending, key)
+		}
+	}
+}
+
+// reportConn reports the connection of the mux to m as a connection of transport, until it stops.
+func (m *clientMux) reportConn(metrics Metrics, transport string) {
+	if metrics == nil {
+		return
+	}
+	closed := openConn(metrics, transport)
+	go func() {
+		<-m.done
+		closed()
+	}()
+}
+
+// readLoop delivers incoming messages until the connection fails.
+func (m *clientMux) readLoop() {
+	for {
+		data, err := m.conn.readMessage()
+		if err != nil {
+			m.stop(fmt.Errorf("%w: %w", ErrConnectionLost, err))
+			return
+		}
+		m.dispatch(data)
+	}
+}
+
+// dispatch routes an incoming message to the pending call it answers or to the notification handler.
+func (m *clientMux) dispatch(data []byte) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return
+	}
+
+	if data[0] == '[' {
+		var envelopes []messageEnvelope
+		if err := json.Unmarshal(data, &envelopes); err != nil {
+			return
+		}
+		orphan := true
+		for _, env := range envelopes {
+			if m.deliver(env.key(), data) {
+				return
+			}
+			orphan = orphan && !env.hasID()
+		}
+		if orphan {
+			m.deliverOrphan(data)
+		}
+		return
+	}
+
+	var env messageEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return
+	}
+	if env.Method != "" {
+		if m.onNotify != nil {
+			var req Request
+			if err := json.Unmarshal(data, &req); err == nil {
+				m.onNotify(context.Background(), &req)
+			}
+		}
+		return
+	}
+	// Replies to calls that are no longer waiting, e.g. canceled, are dropped.
+	if !m.deliver(env.key(), data) && !env.hasID() {
+		m.deliverOrphan(data)
+	}
+}
+
+// deliver hands data to the pending call waiting for the ID key and reports whether there was one.
+func (m *clientMux) deliver(key string, data []byte) bool {
+	if key == "" || key == "null" {
+		return false
+	}
+	m.mu.Lock()
+	p, ok := m.pending[key]
+	if ok {
+		for _, k := range p.keys {
+			delete(m.pending, k)
+		}
+	}
+	m.mu.Unlock()
+
+	if ok {
+		p.reply <- data
+	}
+	return ok
+}
+
+// deliverOrphan hands a response without a usable ID, such as a parse error, to the only pending call, if any.
+// Such responses cannot be matched when several calls are in flight and are dropped.
+func (m *clientMux) deliverOrphan(data []byte) {
+	m.mu.Lock()
+	var only *pendingCall
+	for _, p := range m.pending {
+		if only != nil && only != p {
+			m.mu.Unlock()
+			return
+		}
+		only = p
+	}
+	if only == nil {
+		m.mu.Unlock()
+		return
+	}
+	for _, k := range only.keys {
+		delete(m.pending, k)
+	}
+	m.mu.Unlock()
+
+	only.reply <- data
+}
+
+// stop fails all pending calls with err.
+func (m *clientMux) stop(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return
+	}
+	m.err = err
+	m.pending = make(map[string]*pendingCall)
+	close(m.done)
+}
+
+// stopped reports whether the connection stopped.
+func (m *clientMux) stopped() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection. Pending and later calls fail with [ErrClientClosed].
+func (m *clientMux) close() error {
+	m.stop(ErrClientClosed)
+	return m.conn.Close()
+}
+
+// messageEnvelope holds the fields needed to route an incoming message.
+type messageEnvelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// hasID reports whether the message has an ID other than null.
+func (e messageEnvelope) hasID() bool {
+	key := e.key()
+	return key != "" && key != "null"
+}
+
+// key returns the ID key of the message, see idKey.
+func (e messageEnvelope) key() string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, e.ID); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,148 @@
+// Command jsonrpc2-replay sends the messages of a session recorded by a jsonrpc2.Tap to a server again
+// and reports the replies that differ from the recorded ones.
+//
+// Usage:
+//
+//	jsonrpc2-replay -url http://localhost:8080/rpc session.jsonl
+//
+// The URL selects the transport: http:// or https:// for HTTP, ws:// or wss:// for WebSocket,
+// tcp://host:port for TCP and unix:///path/to/socket for Unix domain sockets.
+// The session is read from standard input if no file is given.
+// The exit status is 1 if a reply differs or a call fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run replays the session and returns the exit status, so that deferred calls run before exiting.
+func run() int {
+	var (
+		endpoint = flag.String("url", "", "URL of the server to replay the session against")
+		timeout  = flag.Duration("timeout", 30*time.Second, "timeout of each call")
+		verbose  = flag.Bool("v", false, "print every message sent and reply received, not only mismatches")
+	)
+	flag.Parse()
+	if *endpoint == "" || flag.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: jsonrpc2-replay -url <url> [session.jsonl]")
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Print("Error opening session: ", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	events, err := jsonrpc2.ReadTap(in)
+	if err != nil {
+		log.Print("Error reading session: ", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := dial(ctx, *endpoint)
+	if err != nil {
+		log.Print("Error connecting to server: ", err)
+		return 1
+	}
+	if c, ok := client.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	results, err := jsonrpc2.Replay(ctx, timeoutClient{client, *timeout}, events)
+	failed := 0
+	for _, r := range results {
+		if r.Match() && !*verbose {
+			continue
+		}
+		if !r.Match() {
+			failed++
+		}
+		fmt.Printf("--> %s\n", r.Event.Message)
+		if r.Recorded != nil {
+			fmt.Printf("<-- recorded: %s\n", r.Recorded)
+		}
+		switch {
+		case r.Err != nil:
+			fmt.Printf("<-- error: %v\n", r.Err)
+		case r.Reply != nil:
+			fmt.Printf("<-- replayed: %s\n", r.Reply)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d messages replayed, %d mismatched\n", len(results), failed)
+	if err != nil {
+		log.Print("Replay interrupted: ", err)
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// dial creates a client for the transport selected by the scheme of endpoint.
+func dial(ctx context.Context, endpoint string) (jsonrpc2.Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	// Keep results as received, so that they are compared exactly.
+	opts := []jsonrpc2.ClientOption{jsonrpc2.WithRawResults()}
+	switch u.Scheme {
+	case "http", "https":
+		return jsonrpc2.NewHTTPClient(endpoint, nil, opts...), nil
+	case "ws", "wss":
+		return jsonrpc2.DialWebSocket(ctx, endpoint, opts...)
+	case "tcp":
+		return jsonrpc2.NewTCPClientWithDialer(jsonrpc2.TCPDialer(u.Host), opts...), nil
+	case "unix":
+		return jsonrpc2.NewTCPClientWithDialer(jsonrpc2.UnixDialer(u.Path), opts...), nil
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+}
+
+// timeoutClient is a client giving up calls after a timeout.
+type timeoutClient struct {
+	jsonrpc2.Client
+	timeout time.Duration
+}
+
+func (c timeoutClient) Call(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Call(ctx, req)
+}
+
+func (c timeoutClient) CallBatch(ctx context.Context, reqs []*jsonrpc2.Request) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.CallBatch(ctx, reqs)
+}
+
+func (c timeoutClient) Notify(ctx context.Context, req *jsonrpc2.Request) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Notify(ctx, req)
+}
@@ -0,0 +1,336 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConnectionLost is returned for calls whose connection failed while the request was in flight.
+	// The server may or may not have processed such a request.
+	ErrConnectionLost = errors.New("jsonrpc2: connection lost")
+	// ErrClientClosed is returned for calls made on a closed client.
+	ErrClientClosed = errors.New("jsonrpc2: client closed")
+)
+
+// PoolStats describes the health of the connections of a client.
+type PoolStats struct {
+	Size                int       // The maximum number of connections.
+	Open                int       // The number of established connections.
+	InUse               int       // The number of connections currently serving a call.
+	ConsecutiveFailures int       // The number of dial and connection failures since the last successful exchange.
+	LastError           error     // The most recent dial or connection error, if any.
+	LastFailure         time.Time // The time of the most recent failure.
+}
+
+// Healthy reports whether no failure happened since the last successful exchange.
+func (s PoolStats) Healthy() bool {
+	return s.ConsecutiveFailures == 0
+}
+
+// connPool is a fixed-size pool of newline-delimited stream connections.
+// Calls are pipelined over each connection, their responses being matched by ID, and spread over the connections by load.
+type connPool struct {
+	dialer   Dialer
+	backoff  Backoff
+	onNotify NotificationHandler
+	tap      *Tap
+	metrics  Metrics
+	slots    []*pooledConn
+
+	maxMessageSize int64
+
+	mu          sync.Mutex
+	closed      bool
+	failures    int
+	lastErr     error
+	lastFailure time.Time
+}
+
+// pooledConn is a slot of a [connPool].
+type pooledConn struct {
+	pool     *connPool
+	dialing  chan struct{} // Held while the slot is dialed, so that concurrent calls wait for a single dial.
+	failures int           // The number of consecutive failed dials of this slot. Guarded by dialing.
+	retryAt  time.Time     // The earliest time the slot may be dialed again. Guarded by dialing.
+
+	mux      *clientMux // The connection of the slot, if any. Guarded by pool.mu.
+	inFlight int        // The number of calls using the slot. Guarded by pool.mu.
+}
+
+// newConnPool creates a new [connPool]. A nil dialer disables reconnects.
+func newConnPool(dialer Dialer, opts *clientOptions) *connPool {
+	size := opts.poolSize
+	if dialer == nil {
+		size = 1
+	}
+	p := &connPool{
+		dialer:   dialer,
+		backoff:  opts.backoff,
+		onNotify: opts.notificationHandler,
+		tap:      opts.tap,
+		metrics:  opts.metrics,
+
+		maxMessageSize: opts.maxMessageSize,
+	}
+	for range size {
+		p.slots = append(p.slots, &pooledConn{pool: p, dialing: make(chan struct{}, 1)})
+	}
+	return p
+}
+
+// call sends data, the encoding of the requests with the given ID keys, and waits for the response.
+func (p *connPool) call(ctx context.Context, data []byte, keys []string) ([]byte, error) {
+	var reply []byte
+	err := p.use(ctx, func(m *clientMux) error {
+		var err error
+		reply, err = m.call(ctx, data, keys)
+		return err
+	})
+	return reply, err
+}
+
+// notify sends data without waiting for a response.
+func (p *connPool) notify(ctx context.Context, data []byte) error {
+	return p.use(ctx, func(m *clientMux) error {
+		return m.notify(ctx, data)
+	})
+}
+
+// use runs fn with the connection of the least loaded slot, dialing it if needed.
+func (p *connPool) use(ctx context.Context, fn func(m *clientMux) error) error {
+	pc, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.release(pc)
+
+	m, err := pc.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(m); err != nil {
+		if errors.Is(err, ErrConnectionLost) {
+			p.recordFailure(err)
+		}
+		return err
+	}
+	p.succeed()
+	return nil
+}
+
+// acquire picks the slot with the fewest calls in flight, preferring connected slots.
+func (p *connPool) acquire() (*pooledConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrClientClosed
+	}
+	var best *pooledConn
+	for _, pc := range p.slots {
+		if best == nil || pc.inFlight < best.inFlight || (pc.inFlight == best.inFlight && pc.connected() && !best.connected()) {
+			best = pc
+		}
+	}
+	best.inFlight++
+	return best, nil
+}
+
+// release records that a call no longer uses a slot.
+func (p *connPool) release(pc *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc.inFlight--
+}
+
+// succeed records a successful exchange.
+func (p *connPool) succeed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = 0
+}
+
+// recordFailure records a failed dial or exchange.
+func (p *connPool) recordFailure(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	p.lastErr = err
+	p.lastFailure = time.Now()
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := PoolStats{
+		Size:                len(p.slots),
+		ConsecutiveFailures: p.failures,
+		LastError:           p.lastErr,
+		LastFailure:         p.lastFailure,
+	}
+	for _, pc := range p.slots {
+		if pc.connected() {
+			s.Open++
+		}
+		if pc.inFlight > 0 {
+			s.InUse++
+		}
+	}
+	return s
+}
+
+func (p *connPool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []error
+	for _, pc := range p.slots {
+		if pc.mux != nil {
+			if err := pc.mux.close(); err != nil {
+				errs = append(errs, err)
+			}
+			pc.mux = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// connected reports whether the slot has a live connection. The caller must hold pool.mu.
+func (pc *pooledConn) connected() bool {
+	return pc.mux != nil && !pc.mux.stopped()
+}
+
+// connect returns the connection of the slot, dialing it if it has none, honoring the reconnect backoff.
+func (pc *pooledConn) connect(ctx context.Context) (*clientMux, error) {
+	select {
+	case pc.dialing <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to dial: %w", ctx.Err())
+	}
+	defer func() { <-pc.dialing }()
+
+	p := pc.pool
+	p.mu.Lock()
+	m := pc.mux
+	p.mu.Unlock()
+	if m != nil && !m.stopped() {
+		return m, nil
+	}
+	if p.dialer == nil {
+		return nil, fmt.Errorf("%w: no dialer to reconnect with", ErrConnectionLost)
+	}
+
+	if err := sleep(ctx, time.Until(pc.retryAt)); err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	conn, err := p.dialer(ctx)
+	if err != nil {
+		pc.failures++
+		pc.retryAt = time.Now().Add(p.backoff.Delay(pc.failures))
+		p.recordFailure(err)
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	pc.failures = 0
+	pc.retryAt = time.Time{}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		conn.Close()
+		return nil, ErrClientClosed
+	}
+	pc.setConn(conn)
+	return pc.mux, nil
+}
+
+// setConn attaches conn to the slot. The caller must hold pool.mu or own the pool exclusively.
+func (pc *pooledConn) setConn(conn net.Conn) {
+	pc.mux = newClientMux(tapMessages(newLineConn(conn, pc.pool.maxMessageSize), pc.pool.tap.openConn(TapClient, conn)), pc.pool.onNotify)
+	pc.mux.reportConn(pc.pool.metrics, conn.LocalAddr().Network())
+}
+
+// lineConn is a [messageConn] exchanging newline-delimited messages over a stream connection.
+type lineConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	maxMessageSize int64
+	writeMu        sync.Mutex
+}
+
+// newLineConn creates a new [lineConn] over conn, reading lines of up to maxMessageSize bytes.
+func newLineConn(conn net.Conn, maxMessageSize int64) *lineConn {
+	return &lineConn{
+		conn:           conn,
+		reader:         bufio.NewReader(conn),
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// readMessage reads the next line. Lines longer than the maximum message size fail, as the next line cannot be found.
+func (c *lineConn) readMessage() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := c.reader.ReadSlice('\n')
+		if int64(len(line)+len(chunk)) > c.maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", c.maxMessageSize)
+		}
+		line = append(line, chunk...)
+		switch {
+		case err == nil:
+			return line, nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil, fmt.Errorf("connection closed: %w", err)
+		default:
+			return nil, err
+		}
+	}
+}
+
+// writeMessage writes data as a single line, giving up when ctx is done.
+func (c *lineConn) writeMessage(ctx context.Context, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set connection deadline: %w", err)
+	}
+	// Unblock the write when ctx is canceled without a deadline.
+	cancelled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(cancelled)
+		c.conn.SetWriteDeadline(time.Now())
+	})
+	defer func() {
+		if !stop() {
+			<-cancelled
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", errNotSent, err)
+	}
+	if n, err := c.conn.Write(append(data, '\n')); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && n == 0 {
+			return fmt.Errorf("%w: %w", errNotSent, ctxErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *lineConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,213 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errorCodes holds the messages of the known error codes and the mappings of Go errors to codes,
+// see [RegisterErrorCode], [MapError] and [MapErrorType].
+var errorCodes = struct {
+	sync.RWMutex
+	messages map[ErrorCode]string
+	mappings []func(err error) (Error, bool)
+}{
+	messages: map[ErrorCode]string{
+		ParseError:     "Parse error",
+		InvalidRequest: "Invalid Request",
+		MethodNotFound: "Method not found",
+		InvalidParams:  "Invalid params",
+		InternalError:  "Internal error",
+		Unauthorized:   "Unauthorized",
+		Forbidden:      "Forbidden",
+		LimitExceeded:  "Limit exceeded",
+	},
+}
+
+// RegisterErrorCode declares an application error code with its default message, e.g.
+//
+//	const UserNotFound jsonrpc2.ErrorCode = 1001
+//
+//	if err := jsonrpc2.RegisterErrorCode(UserNotFound, "User not found"); err != nil {
+//		return err
+//	}
+//
+// Handlers built with [Func] or [RegisterService] may then return [CodeError](UserNotFound), possibly wrapped.
+// Codes must be in the server error range, from -32000 to -32099, or outside the range reserved by the specification,
+// from -32768 to -32000. It returns an error if code is reserved or already declared.
+func RegisterErrorCode(code ErrorCode, message string) error {
+	if code >= -32768 && code <= -32000 && !code.isServerError() {
+		return fmt.Errorf("error code %d is reserved by the specification", code)
+	}
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+	if _, exists := errorCodes.messages[code]; exists {
+		return fmt.Errorf("error code %d is already registered", code)
+	}
+	errorCodes.messages[code] = message
+	return nil
+}
+
+// CodeError is an error standing for an [ErrorCode], so that handlers can return a code as an error
+// and errors can be matched by code with [errors.Is], e.g. errors.Is(err, jsonrpc2.CodeError(jsonrpc2.MethodNotFound)).
+type CodeError ErrorCode
+
+// Error implements the error interface.
+func (c CodeError) Error() string {
+	return fmt.Sprintf("JSON-RPC Error %d: %s", c, ErrorCode(c).Message())
+}
+
+// MapError makes errors matching target, as reported by [errors.Is], convert to errors with code, see [AsError].
+func MapError(target error, code ErrorCode) {
+	addErrorMapping(func(err error) (Error, bool) {
+		if !errors.Is(err, target) {
+			return Error{}, false
+		}
+		return *NewError(code, code.Message(), WithCause(err)), true
+	})
+}
+
+// MapErrorType makes errors of type E, as found by [errors.As], convert to errors with code, see [AsError].
+// The error of type E is sent as the Data of the error, so it should not hold anything clients must not see.
+func MapErrorType[E error](code ErrorCode) {
+	addErrorMapping(func(err error) (Error, bool) {
+		var target E
+		if !errors.As(err, &target) {
+			return Error{}, false
+		}
+		return *NewError(code, code.Message(), WithData(target), WithCause(err)), true
+	})
+}
+
+// addErrorMapping adds a mapping of Go errors to errors, tried after the mappings added before.
+func addErrorMapping(mapping func(err error) (Error, bool)) {
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+	errorCodes.mappings = append(errorCodes.mappings, mapping)
+}
+
+// AsError converts err returned by a handler to the [Error] sent to the client.
+// An [Error] or *[Error] found in err by [errors.As] is sent as is, and a [CodeError] is sent with the message of its code.
+// Otherwise, the mappings added by [MapError] and [MapErrorType] are tried in order, and other errors are answered with an [InternalError].
+// The returned error wraps err, so that it can be inspected on the server, e.g. by logging middleware.
+func AsError(err error) Error {
+	var rpcErr Error
+	var rpcErrPtr *Error
+	var codeErr CodeError
+	switch {
+	case errors.As(err, &rpcErr):
+	case errors.As(err, &rpcErrPtr) && rpcErrPtr != nil:
+		rpcErr = *rpcErrPtr
+	case errors.As(err, &codeErr):
+		code := ErrorCode(codeErr)
+		return *NewError(code, code.Message(), WithCause(err))
+	default:
+		errorCodes.RLock()
+		mappings := errorCodes.mappings
+		errorCodes.RUnlock()
+		for _, mapping := range mappings {
+			if rpcErr, ok := mapping(err); ok {
+				return rpcErr
+			}
+		}
+		return *NewError(InternalError, InternalError.Message(), WithCause(err))
+	}
+
+	// Errors returned as is are not their own cause.
+	switch err.(type) {
+	case Error, *Error:
+	default:
+		if rpcErr.cause == nil {
+			rpcErr.cause = err
+		}
+	}
+	return rpcErr
+}
+
+// Message returns the message of the code defined by the specification or declared by [RegisterErrorCode].
+// Unknown codes have a generic message.
+func (c ErrorCode) Message() string {
+	errorCodes.RLock()
+	message, ok := errorCodes.messages[c]
+	errorCodes.RUnlock()
+	switch {
+	case ok:
+		return message
+	case c.isServerError():
+		return "Server error"
+	default:
+		return "Application error"
+	}
+}
+
+// isServerError reports whether the code is in the range reserved for implementation-defined server errors.
+func (c ErrorCode) isServerError() bool {
+	return c >= -32099 && c <= -32000
+}
+
+// WithCause sets the Go error an [Error] was converted from, as returned by its Unwrap method.
+// The cause is not sent to the client.
+func WithCause(err error) NewErrorOption {
+	return func(e *Error) {
+		e.cause = err
+	}
+}
+
+// Unwrap returns the Go error the error was converted from, if any, see [WithCause].
+func (e Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the error has the code of target, a [CodeError], [Error] or *[Error], so that
+// errors.Is(err, jsonrpc2.CodeError(jsonrpc2.MethodNotFound)) reports whether err is an error with that code.
+func (e Error) Is(target error) bool {
+	switch t := target.(type) {
+	case CodeError:
+		return e.Code == ErrorCode(t)
+	case Error:
+		return e.Code == t.Code
+	case *Error:
+		return t != nil && e.Code == t.Code
+	default:
+		return false
+	}
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], keeping the Data field as a [json.RawMessage] to be decoded by [Error.DecodeData].
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Code    ErrorCode       `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Error{Code: v.Code, Message: v.Message}
+	if len(v.Data) > 0 {
+		e.Data = v.Data
+	}
+	return nil
+}
+
+// DecodeData decodes the Data field into the value pointed to by v, e.g. a *[LimitExceededData].
+func (e Error) DecodeData(v any) error {
+	var data []byte
+	switch d := e.Data.(type) {
+	case nil:
+		return errors.New("error has no data")
+	case json.RawMessage:
+		data = d
+	default:
+		var err error
+		if data, err = json.Marshal(d); err != nil {
+			return fmt.Errorf("failed to marshal data: %w", err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+	return nil
+}
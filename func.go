@@ -0,0 +1,34 @@
+package jsonrpc2
+
+import (
+	"context"
+)
+
+// Func returns a [Handler] that decodes the params into P, calls fn and encodes its result.
+// Params may be given by name or by position, see [UnmarshalParams].
+// Params that cannot be decoded are answered with an [InvalidParams] error, and missing params leave P zero.
+// Errors returned by fn are converted to the error sent to the client by [AsError].
+func Func[P, R any](fn func(ctx context.Context, params P) (R, error)) Handler {
+	return func(ctx context.Context, req *Request) *Response {
+		var params P
+		if len(req.Params) > 0 && string(req.Params) != "null" {
+			if err := UnmarshalParams(req.Params, &params); err != nil {
+				return newErrorResponse(req.ID, InvalidParams, "Invalid params", WithData(err.Error()))
+			}
+		}
+
+		result, err := fn(ctx, params)
+		if err != nil {
+			return NewResponse(req.ID, WithError(AsError(err)))
+		}
+		return NewResponse(req.ID, WithResult(result))
+	}
+}
+
+// RegisterFunc registers fn as the handler of method on s, see [Func].
+// If s is a [Describer], the schemas of the params and result derived from P and R with [SchemaFor] are published
+// by rpc.discover, unless set by opts. Params are only validated against a schema set with [WithParamsSchema].
+func RegisterFunc[P, R any](s Server, method string, fn func(ctx context.Context, params P) (R, error), opts ...RegisterOption) {
+	opts = append([]RegisterOption{withDerivedSchemas(SchemaFor[P](), SchemaFor[R]())}, opts...)
+	registerMethod(s, method, Func(fn), opts)
+}
@@ -4,138 +4,422 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 const (
-	defaultHTTPMethod    = http.MethodPost
-	defaultSuccessStatus = http.StatusOK
+	defaultHTTPMethod  = http.MethodPost
+	defaultContentType = "application/json"
+
+	// maxErrorBodySize is the maximum number of bytes of an unexpected HTTP response kept in [HTTPError].
+	maxErrorBodySize = 64 << 10
 )
 
 // HTTPClient is a JSON-RPC 2.0 client that communicates over HTTP.
 type HTTPClient struct {
-	endpoint string
-	client   *http.Client
+	endpoint   string
+	client     *http.Client
+	header     http.Header
+	auth       HTTPAuth
+	userAgent  string
+	getMethods map[string]bool
+	maxSize    int64
+	onNotify   NotificationHandler
+	decoding   resultDecoding
+	tap        *Tap
+	metrics    Metrics
 }
 
 // NewHTTPClient creates a new [HTTPClient].
-func NewHTTPClient(endpoint string, client *http.Client) *HTTPClient {
+// Use [WithHTTPHeader], [WithHTTPAuth] and [WithUserAgent] to customize the HTTP requests it sends.
+// With [WithNotificationHandler], the client accepts responses streamed as server-sent events
+// and passes the notifications sent before the response to the handler.
+func NewHTTPClient(endpoint string, client *http.Client, opts ...ClientOption) *HTTPClient {
 	if client == nil {
 		client = &http.Client{}
 	}
+	o := newClientOptions(opts)
 	return &HTTPClient{
-		endpoint: endpoint,
-		client:   client,
+		endpoint:   endpoint,
+		client:     client,
+		header:     o.httpHeader,
+		auth:       o.httpAuth,
+		userAgent:  o.userAgent,
+		getMethods: o.getMethods,
+		maxSize:    o.maxMessageSize,
+		onNotify:   o.notificationHandler,
+		decoding:   o.resultDecoding,
+		tap:        o.tap,
+		metrics:    o.metrics,
 	}
 }
 
-var _ Client = (*HTTPClient)(nil)
+// WithHTTPHeader adds a header sent with every request of an [HTTPClient].
+func WithHTTPHeader(key, value string) ClientOption {
+	return func(o *clientOptions) {
+		if o.httpHeader == nil {
+			o.httpHeader = make(http.Header)
+		}
+		o.httpHeader.Add(key, value)
+	}
+}
 
-// Call sends a JSON-RPC request over HTTP and returns the response.
-func (c *HTTPClient) Call(ctx context.Context, req *Request) (*Response, error) {
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// WithHTTPAuth sets the [HTTPAuth] an [HTTPClient] uses to authorize every request.
+func WithHTTPAuth(auth HTTPAuth) ClientOption {
+	return func(o *clientOptions) {
+		o.httpAuth = auth
 	}
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+// WithUserAgent sets the User-Agent header of the requests of an [HTTPClient].
+func WithUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+}
 
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+// HTTPAuth is a function type that adds credentials to an outgoing HTTP request.
+type HTTPAuth func(ctx context.Context, req *http.Request) error
+
+// BearerAuth returns an [HTTPAuth] that sends the token returned by token as a bearer token.
+// token is called for every request, so it may refresh expired tokens.
+func BearerAuth(token func(ctx context.Context) (string, error)) HTTPAuth {
+	return func(ctx context.Context, req *http.Request) error {
+		t, err := token(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+}
+
+// StaticBearerAuth returns an [HTTPAuth] that sends a fixed bearer token.
+func StaticBearerAuth(token string) HTTPAuth {
+	return BearerAuth(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// BasicAuth returns an [HTTPAuth] that uses HTTP basic authentication.
+func BasicAuth(username, password string) HTTPAuth {
+	return func(_ context.Context, req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	}
+}
+
+// httpHeaderKey is the context key for per-call HTTP headers.
+type httpHeaderKey struct{}
+
+// ContextWithHTTPHeader returns a copy of ctx carrying header.
+// [HTTPClient] sends these headers in addition to its static ones, overriding headers with the same name.
+// Headers already carried by ctx are kept unless overridden.
+func ContextWithHTTPHeader(ctx context.Context, header http.Header) context.Context {
+	merged := HTTPHeaderFromContext(ctx).Clone()
+	if merged == nil {
+		merged = make(http.Header, len(header))
+	}
+	for k, v := range header {
+		merged[http.CanonicalHeaderKey(k)] = slices.Clone(v)
+	}
+	return context.WithValue(ctx, httpHeaderKey{}, merged)
+}
+
+// HTTPHeaderFromContext returns the per-call HTTP headers carried by ctx, or nil.
+func HTTPHeaderFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(httpHeaderKey{}).(http.Header)
+	return header
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+var _ BatchCaller = (*HTTPClient)(nil)
+
+// Call sends a JSON-RPC request over HTTP and returns the response.
+// Requests for methods set with [WithHTTPGetMethods] are sent with GET, see [WithHTTPGetMethod].
+// The HTTP headers of the response are available through [Response.HTTPHeader].
+func (c *HTTPClient) Call(ctx context.Context, req *Request) (reply *Response, err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(reply, err) }()
+
+	if req.IsNotification() {
+		return nil, ErrMissingID
+	}
+
+	tap := c.tap.open(TapClient, "http", c.endpoint)
+	var resp *http.Response
+	get := c.getMethods[req.Method]
+	if get {
+		getURL, err := getRequestURL(c.endpoint, req)
+		if err != nil {
+			return nil, err
+		}
+		if tap != nil {
+			reqData, _ := json.Marshal(req)
+			tap.record(TapSend, reqData)
+		}
+		resp, err = c.do(ctx, http.MethodGet, getURL, nil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		tap.record(TapSend, body)
+		resp, err = c.do(ctx, defaultHTTPMethod, c.endpoint, body)
+		if err != nil {
+			return nil, err
+		}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != defaultSuccessStatus {
-		return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	// A 204 No Content carries no response, e.g. from a server taking the request for a notification.
+	if !successful(resp.StatusCode) || resp.StatusCode == http.StatusNoContent {
+		httpErr := newHTTPError(resp)
+		tap.record(TapReceive, httpErr.Body)
+		rpcResp, ok := httpErr.errorResponse()
+		if !ok {
+			return nil, httpErr
+		}
+		rpcResp.header = resp.Header
+		if get {
+			rpcResp.ID = req.ID
+		}
+		return rpcResp, nil
 	}
 
-	var rpcResp Response
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	data, err := c.readReply(ctx, resp, tap)
+	if err != nil {
+		return nil, err
+	}
+	rpcResp, err := decodeResponse(data, c.decoding)
+	if err != nil {
+		return nil, err
+	}
+	rpcResp.header = resp.Header
+	if get {
+		// The ID is not sent with GET, see getRequestURL.
+		rpcResp.ID = req.ID
 	}
 
-	return &rpcResp, nil
+	return rpcResp, nil
+}
+
+// Go sends a JSON-RPC request over HTTP without waiting for the response, see [Call].
+func (c *HTTPClient) Go(ctx context.Context, req *Request) *Call {
+	return Go(ctx, req, c.Call)
 }
 
 // CallBatch sends a batch of JSON-RPC requests over HTTP and returns the responses.
+// It returns nil if the server sends no reply, as to a batch of notifications.
 func (c *HTTPClient) CallBatch(ctx context.Context, reqs []*Request) (any, error) {
-	body, err := json.Marshal(reqs)
+	data, err := c.sendBatch(ctx, reqs)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	rpcResp, err := decodeBatch(data, c.decoding)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+		return nil, fmt.Errorf("failed to decode response as single or batch: %w", err)
 	}
+	return rpcResp, nil
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, defaultHTTPMethod, c.endpoint, bytes.NewReader(body))
+// CallBatchResponses implements [BatchCaller].
+func (c *HTTPClient) CallBatchResponses(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	data, err := c.sendBatch(ctx, reqs)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return decodeResponses(data, c.decoding)
+}
+
+// sendBatch sends a batch of JSON-RPC requests over HTTP and returns the reply undecoded.
+// Error responses sent with a non-2xx status are returned as replies, and a reply without body, e.g. the 204 No Content
+// answering a batch of notifications, is returned as nil.
+func (c *HTTPClient) sendBatch(ctx context.Context, reqs []*Request) (reply []byte, err error) {
+	done := startBatch(c.metrics, reqs)
+	defer func() { done(reply, err) }()
+
+	body, err := json.Marshal(reqs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(httpReq)
+	tap := c.tap.open(TapClient, "http", c.endpoint)
+	tap.record(TapSend, body)
+	resp, err := c.do(ctx, defaultHTTPMethod, c.endpoint, body)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != defaultSuccessStatus {
-		return nil, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	if !successful(resp.StatusCode) {
+		httpErr := newHTTPError(resp)
+		tap.record(TapReceive, httpErr.Body)
+		if _, ok := httpErr.errorResponse(); !ok {
+			return nil, httpErr
+		}
+		return httpErr.Body, nil
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
 	}
 
-	var rpcResp any
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response as single or batch: %w", err)
+	data, err := c.readReply(ctx, resp, tap)
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil, err
 	}
-	return rpcResp, nil
+	return data, nil
 }
 
-// Notify sends a JSON-RPC notification over HTTP.
-func (c *HTTPClient) Notify(ctx context.Context, req *Request) error {
-	body, err := json.Marshal(req)
+// Notify sends a JSON-RPC notification over HTTP. The ID of req, if any, is not sent.
+// Any 2xx status is accepted and the response body is ignored.
+func (c *HTTPClient) Notify(ctx context.Context, req *Request) (err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(nil, err) }()
+
+	body, err := json.Marshal(req.asNotification())
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, defaultHTTPMethod, c.endpoint, bytes.NewReader(body))
+	c.tap.open(TapClient, "http", c.endpoint).record(TapSend, body)
+	resp, err := c.do(ctx, defaultHTTPMethod, c.endpoint, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !successful(resp.StatusCode) {
+		return newHTTPError(resp)
+	}
+	return nil
+}
+
+// successful reports whether an HTTP status code is a 2xx success.
+func successful(code int) bool {
+	return code >= 200 && code <= 299
+}
+
+// do sends an HTTP request with the client's headers and credentials. A nil body sends no body.
+func (c *HTTPClient) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", defaultContentType)
+	}
+	httpReq.Header.Set("Accept", defaultContentType)
+	if c.onNotify != nil {
+		httpReq.Header.Set("Accept", defaultContentType+", "+eventStreamContentType)
+	}
+	if c.userAgent != "" {
+		httpReq.Header.Set("User-Agent", c.userAgent)
+	}
+	for k, v := range c.header {
+		httpReq.Header[k] = slices.Clone(v)
+	}
+	for k, v := range HTTPHeaderFromContext(ctx) {
+		httpReq.Header[k] = slices.Clone(v)
+	}
+	if c.auth != nil {
+		if err := c.auth(ctx, httpReq); err != nil {
+			return nil, err
+		}
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("HTTP request failed: %w", err)
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
-	defer resp.Body.Close()
+	return resp, nil
+}
 
-	return nil
+// HTTPError is returned by [HTTPClient] when the server answers with an unexpected HTTP status.
+type HTTPError struct {
+	StatusCode int         // The HTTP status code, e.g. 503.
+	Status     string      // The HTTP status line, e.g. "503 Service Unavailable".
+	Header     http.Header // The headers of the HTTP response.
+	Body       []byte      // The beginning of the HTTP response body.
+}
+
+// newHTTPError creates a new [HTTPError] from resp, reading a bounded part of its body.
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %s", e.Status)
+}
+
+// errorResponse decodes the body as a JSON-RPC error response.
+// Servers may answer error responses with a non-200 status, see [WithHTTPStatusCodes].
+func (e *HTTPError) errorResponse() (*Response, bool) {
+	if !isJSONMediaType(e.Header.Get("Content-Type"), false) {
+		return nil, false
+	}
+	var resp Response
+	if err := json.Unmarshal(e.Body, &resp); err != nil || resp.Error == nil {
+		return nil, false
+	}
+	return &resp, true
 }
 
 // HTTPServer is a JSON-RPC 2.0 server that handles HTTP requests.
 type HTTPServer struct {
-	handlers map[string]Handler
-	mux      *http.ServeMux
-	server   *http.Server
+	*dispatcher
+	path        string
+	mux         *http.ServeMux
+	server      *http.Server
+	statusCodes map[ErrorCode]int
+	getMethods  map[string]HTTPCachePolicy
+	eventStream bool
 }
 
 // NewHTTPServer creates a new [HTTPServer] with an empty handlers.
-func NewHTTPServer(addr, path string) *HTTPServer {
+func NewHTTPServer(addr, path string, opts ...ServerOption) *HTTPServer {
 	mux := http.NewServeMux()
 	server := &http.Server{
 		Addr:    addr,
 		Handler: mux,
 	}
 
+	o := newServerOptions(opts)
+	if o.metrics != nil {
+		server.ConnState = connStateMetrics(o.metrics)
+	}
 	s := &HTTPServer{
-		handlers: make(map[string]Handler),
-		mux:      mux,
-		server:   server,
+		dispatcher:  newDispatcher(o),
+		path:        path,
+		mux:         mux,
+		server:      server,
+		statusCodes: o.httpStatusCodes,
+		getMethods:  o.httpGetMethods,
+		eventStream: o.eventStream,
 	}
 
 	// Register the JSON-RPC handler on the specified path
@@ -144,11 +428,63 @@ func NewHTTPServer(addr, path string) *HTTPServer {
 	return s
 }
 
-var _ Server = (*HTTPServer)(nil)
+// connStateMetrics returns an [http.Server] ConnState hook reporting its connections to m as "http" connections.
+// Connections taken over by a handler are reported closed, as the server no longer tracks them.
+func connStateMetrics(m Metrics) func(net.Conn, http.ConnState) {
+	return func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			m.ConnOpened("http")
+		case http.StateHijacked, http.StateClosed:
+			m.ConnClosed("http")
+		}
+	}
+}
+
+// DefaultHTTPStatusCodes maps error codes to the HTTP status [HTTPServer] answers with by default.
+// Responses with other error codes are sent with 200 OK.
+var DefaultHTTPStatusCodes = map[ErrorCode]int{
+	ParseError:     http.StatusBadRequest,
+	InvalidRequest: http.StatusBadRequest,
+	Unauthorized:   http.StatusUnauthorized,
+	Forbidden:      http.StatusForbidden,
+	LimitExceeded:  http.StatusTooManyRequests,
+}
+
+// ConventionalHTTPStatusCodes maps error codes to HTTP statuses as described by the JSON-RPC over HTTP conventions.
+// Use it with [WithHTTPStatusCodes] for proxies that rely on the HTTP status.
+var ConventionalHTTPStatusCodes = map[ErrorCode]int{
+	ParseError:     http.StatusInternalServerError,
+	InvalidRequest: http.StatusBadRequest,
+	MethodNotFound: http.StatusNotFound,
+	InvalidParams:  http.StatusInternalServerError,
+	InternalError:  http.StatusInternalServerError,
+	Unauthorized:   http.StatusUnauthorized,
+	Forbidden:      http.StatusForbidden,
+	LimitExceeded:  http.StatusTooManyRequests,
+}
+
+// WithHTTPStatusCodes sets the HTTP status an [HTTPServer] answers with for single responses carrying an error.
+// Error codes missing from codes are answered with 200 OK. Batch responses are always answered with 200 OK.
+func WithHTTPStatusCodes(codes map[ErrorCode]int) ServerOption {
+	return func(o *serverOptions) {
+		o.httpStatusCodes = codes
+	}
+}
+
+var _ Describer = (*HTTPServer)(nil)
+
+var _ http.Handler = (*HTTPServer)(nil)
 
 // Register registers a handler for a specific method.
 func (s *HTTPServer) Register(method string, handler Handler) {
-	s.handlers[method] = handler
+	s.dispatcher.Register(method, handler)
+}
+
+// RegisterMethod registers a handler for a specific method.
+// The options describe the method in the document served by rpc.discover, see [RegisterOption].
+func (s *HTTPServer) RegisterMethod(method string, handler Handler, opts ...RegisterOption) {
+	s.dispatcher.RegisterMethod(method, handler, opts...)
 }
 
 // Run starts the HTTP server and listens for incoming requests.
@@ -161,43 +497,219 @@ func (s *HTTPServer) Run(ctx context.Context) error {
 	return s.server.ListenAndServe()
 }
 
+// ServeHTTP handles JSON-RPC requests sent to the configured path, so that the server can be mounted on another
+// [http.ServeMux], wrapped by middleware or served by an [net/http/httptest.Server] instead of being run.
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Path returns the path the server handles JSON-RPC requests on.
+func (s *HTTPServer) Path() string {
+	return s.path
+}
+
 // handleJSONRPC handles incoming JSON-RPC requests over HTTP.
 func (s *HTTPServer) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && len(s.getMethods) > 0 {
+		s.handleGet(w, r)
+		return
+	}
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		s.writeMethodNotAllowed(w)
 		return
 	}
 
-	if r.Header.Get("Content-Type") != "application/json" {
-		http.Error(w, "Content-Type must be application/json", http.StatusBadRequest)
+	if !isJSONMediaType(r.Header.Get("Content-Type"), false) {
+		s.writeError(w, http.StatusUnsupportedMediaType, InvalidRequest, "Content-Type must be application/json")
 		return
 	}
 
-	var req Request
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&req); err != nil {
-		errorResp := NewError(ParseError, "Parse error")
-		resp := NewResponse(nil, WithError(*errorResp))
-		s.writeResponse(w, resp)
+	accept := r.Header.Values("Accept")
+	streamable := s.eventStream && acceptsEventStream(accept)
+	if !acceptsJSON(accept) && !streamable {
+		s.writeError(w, http.StatusNotAcceptable, InvalidRequest, "Accept must allow application/json")
 		return
 	}
 
-	if handler, exists := s.handlers[req.Method]; exists {
-		resp := handler(r.Context(), &req)
-		s.writeResponse(w, resp)
-	} else {
-		err := NewError(MethodNotFound, "Method not found")
-		resp := NewResponse(req.ID, WithError(*err))
-		s.writeResponse(w, resp)
+	ctx, ok := s.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if s.maxMessageSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxMessageSize)
+	}
+	body, err := io.ReadAll(r.Body)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		s.writeResponse(w, http.StatusRequestEntityTooLarge, s.reject(tooLarge("size")))
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, ParseError, "Parse error")
+		return
+	}
+	tap := s.tap.open(TapServer, "http", r.RemoteAddr)
+	tap.record(TapReceive, body)
+	w = tapResponses(w, tap)
+
+	var stream *eventStream
+	if streamable {
+		stream = newEventStream(w, !acceptsJSON(accept))
+		ctx = contextWithNotifier(ctx, stream)
+	}
+	reply := s.handleMessage(ctx, body)
+	if stream != nil && stream.finish(reply) {
+		return
+	}
+
+	switch reply := reply.(type) {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case *Response:
+		s.writeResponse(w, s.statusCode(reply), reply)
+	default:
+		s.writeResponse(w, http.StatusOK, reply)
+	}
+}
+
+// requestPeer describes the client r was received from.
+func requestPeer(r *http.Request) *Peer {
+	return &Peer{Network: "tcp", RemoteAddr: r.RemoteAddr, TLS: r.TLS}
+}
+
+// requestHeaderKey is the context key for the headers of the HTTP request a request was received with.
+type requestHeaderKey struct{}
+
+// RequestHeaderFromContext returns the headers of the HTTP request, or of the WebSocket opening handshake,
+// a request was received with. It returns nil for requests received over other transports.
+func RequestHeaderFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(requestHeaderKey{}).(http.Header)
+	return header
+}
+
+// contextWithRequest returns a copy of ctx carrying the peer and the headers of r.
+func contextWithRequest(ctx context.Context, r *http.Request) context.Context {
+	ctx = context.WithValue(ctx, requestHeaderKey{}, r.Header)
+	return contextWithPeer(ctx, requestPeer(r))
+}
+
+// authenticateRequest returns the context the handlers of r are called with.
+// If r cannot be authenticated, it answers 401 Unauthorized and reports false.
+func (s *HTTPServer) authenticateRequest(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx := contextWithRequest(r.Context(), r)
+	peer, _ := PeerFromContext(ctx)
+	ctx, err := s.startSession(ctx, &Credentials{Header: r.Header, Peer: peer})
+	if err != nil {
+		s.writeResponse(w, http.StatusUnauthorized, NewResponse(nil, WithError(s.authErr())))
+		return nil, false
+	}
+	return ctx, true
+}
+
+// statusCode returns the HTTP status to answer a single response with.
+func (s *HTTPServer) statusCode(resp *Response) int {
+	if resp.Error == nil {
+		return http.StatusOK
+	}
+	codes := s.statusCodes
+	if codes == nil {
+		codes = DefaultHTTPStatusCodes
+	}
+	if status, ok := codes[resp.Error.Code]; ok {
+		return status
+	}
+	return http.StatusOK
+}
+
+// writeError writes a JSON-RPC error response with a null ID and the given HTTP status.
+func (s *HTTPServer) writeError(w http.ResponseWriter, status int, code ErrorCode, message string) {
+	s.writeResponse(w, status, newErrorResponse(nil, code, message))
+}
+
+// writeMethodNotAllowed answers a request made with an unsupported HTTP method.
+func (s *HTTPServer) writeMethodNotAllowed(w http.ResponseWriter) {
+	allow := http.MethodPost
+	if len(s.getMethods) > 0 {
+		allow = http.MethodGet + ", " + http.MethodPost
+	}
+	w.Header().Set("Allow", allow)
+	s.writeError(w, http.StatusMethodNotAllowed, InvalidRequest, "Method not allowed")
+}
+
+// writeResponse writes a JSON-RPC response or batch response to the HTTP response writer.
+// A [LimitExceeded] error telling when to retry sets the Retry-After header.
+func (s *HTTPServer) writeResponse(w http.ResponseWriter, status int, reply any) {
+	if resp, ok := reply.(*Response); ok && resp.Error != nil {
+		if data, ok := resp.Error.Data.(*LimitExceededData); ok && data.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(data.RetryAfter))))
+		}
+	}
+	data, err := marshalReply(reply)
+	if err != nil {
+		status = http.StatusInternalServerError
 	}
+	writeJSON(w, status, data)
 }
 
-// writeResponse writes a JSON-RPC response to the HTTP response writer.
-func (s *HTTPServer) writeResponse(w http.ResponseWriter, resp *Response) {
-	w.Header().Set("Content-Type", "application/json")
+// marshalReply encodes a JSON-RPC response or batch response.
+// If reply cannot be encoded, an internal error response is encoded instead.
+func marshalReply(reply any) ([]byte, error) {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		data, _ = json.Marshal(newErrorResponse(nil, InternalError, "Internal error"))
+		return data, err
+	}
+	return data, nil
+}
+
+// writeJSON writes an encoded JSON-RPC message with the given HTTP status.
+func writeJSON(w http.ResponseWriter, status int, data []byte) {
+	w.Header().Set("Content-Type", defaultContentType)
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
 
-	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(resp); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// jsonMediaTypes are the media types accepted for JSON-RPC messages over HTTP.
+var jsonMediaTypes = []string{"application/json", "application/json-rpc", "application/jsonrequest"}
+
+// isJSONMediaType reports whether the Content-Type header value v denotes JSON encoded as UTF-8.
+// If allowWildcard is set, wildcard types like */* are accepted too.
+func isJSONMediaType(v string, allowWildcard bool) bool {
+	mediaType, params, err := mime.ParseMediaType(v)
+	if err != nil {
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+	if allowWildcard && (mediaType == "*/*" || mediaType == "application/*") {
+		return true
+	}
+	return slices.Contains(jsonMediaTypes, mediaType)
+}
+
+// acceptsJSON reports whether the Accept header values allow a JSON response.
+// A missing Accept header allows any response.
+func acceptsJSON(accept []string) bool {
+	if len(accept) == 0 {
+		return true
+	}
+	for _, v := range accept {
+		for _, mediaRange := range strings.Split(v, ",") {
+			if isJSONMediaType(mediaRange, true) && !hasZeroQuality(mediaRange) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasZeroQuality reports whether a media range of an Accept header is explicitly refused with q=0.
+func hasZeroQuality(mediaRange string) bool {
+	_, params, err := mime.ParseMediaType(mediaRange)
+	if err != nil {
+		return false
 	}
+	q, err := strconv.ParseFloat(params["q"], 64)
+	return err == nil && q == 0
 }
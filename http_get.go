@@ -0,0 +1,207 @@
+package jsonrpc2
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPCachePolicy describes how responses to GET requests of a method may be cached.
+type HTTPCachePolicy struct {
+	// The Cache-Control header of successful responses, e.g. "public, max-age=60". Empty sends none.
+	// If the server has an [Authenticator], responses depend on the credentials of the client,
+	// so they are always marked private and vary by Authorization.
+	CacheControl string
+
+	ETag bool // Whether successful responses carry an ETag and conditional requests with If-None-Match are answered with 304.
+}
+
+// WithHTTPGetMethod allows an [HTTPServer] to serve method with GET requests of the form
+//
+//	GET /rpc?method=<method>&params=<JSON>
+//
+// where params is either URL-encoded JSON or base64url-encoded JSON.
+// Requests without id are answered with a null id, so that the URL of a call only depends on its method and params
+// and caches can share responses. An id, a JSON number or string, may still be given with &id=<JSON> to be echoed;
+// a bare id that is not valid JSON is used as a string.
+// Only methods without side effects should be served with GET, as caches may replay or skip calls.
+func WithHTTPGetMethod(method string, policy HTTPCachePolicy) ServerOption {
+	return func(o *serverOptions) {
+		if o.httpGetMethods == nil {
+			o.httpGetMethods = make(map[string]HTTPCachePolicy)
+		}
+		o.httpGetMethods[method] = policy
+	}
+}
+
+// WithHTTPGetMethods makes an [HTTPClient] call methods with GET requests, as served by [WithHTTPGetMethod].
+// Params are sent base64url-encoded. The ID of the request is not sent, so that calls with the same params
+// share cached responses; the response is given the ID of the request. Notifications and batches are always sent with POST.
+func WithHTTPGetMethods(methods ...string) ClientOption {
+	return func(o *clientOptions) {
+		if o.getMethods == nil {
+			o.getMethods = make(map[string]bool)
+		}
+		for _, m := range methods {
+			o.getMethods[m] = true
+		}
+	}
+}
+
+// getRequestURL encodes the method and params of req into the query of endpoint, leaving out its ID.
+func getRequestURL(endpoint string, req *Request) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("method", req.Method)
+	if len(req.Params) > 0 {
+		q.Set("params", base64.RawURLEncoding.EncodeToString(req.Params))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// handleGet handles a JSON-RPC request encoded in the query of a GET request.
+func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsJSON(r.Header.Values("Accept")) {
+		s.writeError(w, http.StatusNotAcceptable, InvalidRequest, "Accept must allow application/json")
+		return
+	}
+
+	query := r.URL.Query()
+	method := query.Get("method")
+	policy, ok := s.getMethods[method]
+	if !ok {
+		s.writeMethodNotAllowed(w)
+		return
+	}
+
+	req := &Request{
+		JSONRPC: version,
+		Method:  method,
+	}
+	if query.Has("params") {
+		params, err := decodeQueryParams(query.Get("params"))
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, ParseError, "Parse error")
+			return
+		}
+		req.Params = params
+	}
+	if query.Has("id") {
+		req.ID = decodeQueryID(query.Get("id"), s.useNumber)
+	}
+
+	// Check the limits as if req had been sent with POST.
+	reqData, _ := json.Marshal(req)
+	if resp := s.checkStructure(reqData); resp != nil {
+		s.writeResponse(w, http.StatusBadRequest, s.reject(resp))
+		return
+	}
+
+	tap := s.tap.open(TapServer, "http", r.RemoteAddr)
+	tap.record(TapReceive, reqData)
+	w = tapResponses(w, tap)
+
+	ctx, ok := s.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	resp := s.handle(ctx, req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, err := marshalReply(resp)
+	if err != nil || resp.Error != nil {
+		w.Header().Set("Cache-Control", "no-store")
+		s.writeResponse(w, s.statusCode(resp), resp)
+		return
+	}
+
+	cacheControl := policy.CacheControl
+	if s.authenticator != nil {
+		if cacheControl != "" {
+			cacheControl = privateCacheControl(cacheControl)
+		}
+		w.Header().Add("Vary", "Authorization")
+	}
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+	w.Header().Add("Vary", "Accept")
+	if policy.ETag {
+		sum := sha256.Sum256(data)
+		etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// decodeQueryParams decodes params given as URL-encoded or base64url-encoded JSON.
+func decodeQueryParams(v string) (json.RawMessage, error) {
+	if trimmed := strings.TrimSpace(v); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if !json.Valid([]byte(trimmed)) {
+			return nil, fmt.Errorf("invalid JSON params")
+		}
+		return json.RawMessage(trimmed), nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode params: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid JSON params")
+	}
+	return data, nil
+}
+
+// decodeQueryID decodes an id given as a JSON number or string, as a request ID received with POST, falling back to v itself.
+func decodeQueryID(v string, useNumber bool) any {
+	var id any
+	if err := decodeJSON([]byte(v), &id, useNumber); err == nil {
+		switch id.(type) {
+		case string, float64, json.Number:
+			return id
+		}
+	}
+	return v
+}
+
+// privateCacheControl returns the Cache-Control value cc with its public or private directive replaced by private,
+// so that shared caches do not serve a response to clients with other credentials.
+func privateCacheControl(cc string) string {
+	directives := []string{"private"}
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" || strings.EqualFold(d, "public") || strings.EqualFold(d, "private") {
+			continue
+		}
+		directives = append(directives, d)
+	}
+	return strings.Join(directives, ", ")
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
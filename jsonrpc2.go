@@ -3,8 +3,12 @@ package jsonrpc2
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 )
 
 const version = "2.0"
@@ -15,6 +19,62 @@ type Request struct {
 	Method  string          `json:"method"`           // The name of the method to be invoked.
 	Params  json.RawMessage `json:"params,omitempty"` // The parameters of the method being invoked.
 	ID      any             `json:"id"`               // A unique identifier for the request.
+
+	notification bool // Whether the request is a notification, encoded without "id" member.
+}
+
+// IsNotification reports whether the request is a notification, i.e. was decoded without "id" member.
+// The server does not reply to notifications. A request with a null ID is not a notification.
+func (r *Request) IsNotification() bool {
+	return r.notification
+}
+
+// MarshalJSON implements [json.Marshaler], leaving out the "id" member of notifications.
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request // Drops the methods of Request to avoid recursion.
+	if r.notification {
+		return json.Marshal(struct {
+			request
+			ID any `json:"id,omitempty"` // Hides the ID of request.
+		}{request: request(r)})
+	}
+	return json.Marshal(request(r))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], telling a null ID from a missing one.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	return r.unmarshal(data, false)
+}
+
+// unmarshal decodes the request encoded in data, decoding a numeric ID as [json.Number] if useNumber is set.
+func (r *Request) unmarshal(data []byte, useNumber bool) error {
+	var v struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*r = Request{JSONRPC: v.JSONRPC, Method: v.Method, Params: v.Params}
+	if v.ID == nil {
+		r.notification = true
+		return nil
+	}
+	return decodeJSON(v.ID, &r.ID, useNumber)
+}
+
+// ErrMissingID is returned when a notification, such as a request decoded without "id" member, is passed to [Client.Call].
+var ErrMissingID = errors.New("jsonrpc2: request has no ID; use Notify to send notifications")
+
+// asNotification returns a copy of the request as a notification, without ID.
+func (r *Request) asNotification() *Request {
+	n := *r
+	n.ID = nil
+	n.notification = true
+	return &n
 }
 
 // UnmarshalRequest unmarshals a [Request] from JSON data.
@@ -44,7 +104,7 @@ func NewRequest(method string, opts ...NewRequestOption) (*Request, error) {
 // NewRequestOption defines a function type for setting optional fields in [Request].
 type NewRequestOption func(*Request) error
 
-// WithParams sets the Params field of a [Request].
+// WithParams sets the Params field of a [Request]. Structs are encoded by name; see [WithPositionalParams] to encode them by position.
 func WithParams(params any) NewRequestOption {
 	return func(r *Request) error {
 		if params == nil {
@@ -73,6 +133,24 @@ type Response struct {
 	Result  any    `json:"result,omitempty"` // The result of the method invocation. This field is omitted if there was an error.
 	Error   *Error `json:"error,omitempty"`  // An error object if an error occurred.
 	ID      any    `json:"id"`               // The same ID as in the request. It is used to match responses to requests.
+
+	header    http.Header     // The HTTP headers the response was received with, if any.
+	rawResult json.RawMessage // The encoding of the result, see [Response.DecodeResult].
+}
+
+// HTTPHeader returns the HTTP headers the response was received with.
+// It returns nil for responses that were not received by [HTTPClient].
+func (r *Response) HTTPHeader() http.Header {
+	return r.header
+}
+
+// Err returns the Error field as an error, or nil if the request succeeded.
+// Codes can be matched with [errors.Is], e.g. errors.Is(resp.Err(), jsonrpc2.CodeError(jsonrpc2.MethodNotFound)).
+func (r *Response) Err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return r.Error
 }
 
 // NewResponse creates a new [Response].
@@ -117,11 +195,22 @@ const (
 	InternalError  ErrorCode = -32603 // Internal JSON-RPC error.
 )
 
+// Implementation-defined server errors, in the range reserved by the specification from -32000 to -32099.
+const (
+	Unauthorized ErrorCode = -32001 // The request is not authenticated, see [WithAuthenticator].
+	Forbidden    ErrorCode = -32003 // The authenticated principal is not allowed to call the method, see [WithAuthorizer].
+	// The request was rejected because the server is over a limit, see [WithMaxConcurrentHandlers].
+	// The Data of the error is a [LimitExceededData].
+	LimitExceeded ErrorCode = -32005
+)
+
 // Error represents a JSON-RPC 2.0 error object.
 type Error struct {
 	Code    ErrorCode `json:"code"`           // A number indicating the error type that occurred
 	Message string    `json:"message"`        // A short description of the error
-	Data    any       `json:"data,omitempty"` // Additional information about the error
+	Data    any       `json:"data,omitempty"` // Additional information about the error. Received errors hold a [json.RawMessage], see [Error.DecodeData].
+
+	cause error // The Go error the error was converted from, see [WithCause].
 }
 
 // NewError creates a new [Error].
@@ -158,11 +247,49 @@ type Client interface {
 	Call(ctx context.Context, req *Request) (*Response, error)
 	// CallBatch sends multiple JSON-RPC 2.0 requests at once and returns their responses.
 	// If a ParseError occurs, returns a single [Response]. Otherwise, returns a slice of [Response].
+	// A batch made only of notifications returns nil, as the server sends no reply.
+	// See [Batch] to get the responses matched to the requests.
 	CallBatch(ctx context.Context, reqs []*Request) (any, error)
 	// Notify sends a JSON-RPC 2.0 notification (no response expected).
 	Notify(ctx context.Context, req *Request) error
 }
 
+// ClientOption defines a function type for setting optional fields of a [Client].
+// Each option names the clients it configures, such as [TCPClient] for [WithPoolSize], or applies to all clients
+// if it says "a client"; other clients ignore it.
+type ClientOption func(*clientOptions)
+
+// clientOptions holds the settings configured by [ClientOption].
+type clientOptions struct {
+	poolSize   int
+	backoff    Backoff
+	httpHeader http.Header
+	httpAuth   HTTPAuth
+	userAgent  string
+	getMethods map[string]bool
+
+	maxMessageSize      int64
+	resultDecoding      resultDecoding
+	notificationHandler NotificationHandler
+	pingInterval        time.Duration
+	tlsConfig           *tls.Config
+	tap                 *Tap
+	metrics             Metrics
+}
+
+// newClientOptions applies opts on top of the default settings.
+func newClientOptions(opts []ClientOption) *clientOptions {
+	o := &clientOptions{
+		poolSize:       1,
+		backoff:        DefaultBackoff,
+		maxMessageSize: defaultMaxClientMessageSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
 // Handler is a function type that processes a JSON-RPC request and returns a response.
 type Handler = func(ctx context.Context, req *Request) *Response
 
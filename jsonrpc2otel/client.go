@@ -0,0 +1,205 @@
+package jsonrpc2otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Client is a [jsonrpc2.Client] creating a client span around each call and notification made through another client,
+// and propagating the trace context to the server.
+// It keeps the behavior of the client it wraps: batches sent with [jsonrpc2.Batch] are decoded as set by
+// [jsonrpc2.WithUseNumber] and [jsonrpc2.WithRawResults], and [Client.Stats] and [Client.Close] reach the wrapped client.
+type Client struct {
+	next   jsonrpc2.Client
+	cfg    *config
+	tracer trace.Tracer
+	meta   bool
+}
+
+// NewClient creates a new [Client] making calls through next.
+func NewClient(next jsonrpc2.Client, opts ...Option) *Client {
+	cfg := newConfig(opts)
+	_, isHTTP := next.(*jsonrpc2.HTTPClient)
+	meta := !isHTTP
+	if cfg.meta != nil {
+		meta = *cfg.meta
+	}
+	return &Client{
+		next:   next,
+		cfg:    cfg,
+		tracer: cfg.tracer(),
+		meta:   meta,
+	}
+}
+
+var _ jsonrpc2.Client = (*Client)(nil)
+
+var _ jsonrpc2.BatchCaller = (*Client)(nil)
+
+// Call sends a request through the client in a span named after its method.
+func (c *Client) Call(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	ctx, span := c.start(ctx, req, req.IsNotification())
+	resp, err := c.next.Call(c.injectHeader(ctx), c.injectMeta(ctx, req))
+	var rpcErr *jsonrpc2.Error
+	if resp != nil {
+		rpcErr = resp.Error
+	}
+	endSpan(span, rpcErr, err)
+	return resp, err
+}
+
+// Go sends a request through the client without waiting for the response, see [Client.Call].
+func (c *Client) Go(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Call {
+	return jsonrpc2.Go(ctx, req, c.Call)
+}
+
+// CallBatch sends requests through the client in a span covering the batch, with a child span for each request.
+func (c *Client) CallBatch(ctx context.Context, reqs []*jsonrpc2.Request) (any, error) {
+	return callBatch(ctx, c, reqs, c.next.CallBatch, batchErrors)
+}
+
+// CallBatchResponses implements [jsonrpc2.BatchCaller], creating spans as [Client.CallBatch] does.
+func (c *Client) CallBatchResponses(ctx context.Context, reqs []*jsonrpc2.Request) ([]*jsonrpc2.Response, error) {
+	send := func(ctx context.Context, reqs []*jsonrpc2.Request) ([]*jsonrpc2.Response, error) {
+		return jsonrpc2.CallBatchResponses(ctx, c.next, reqs)
+	}
+	return callBatch(ctx, c, reqs, send, responseErrors)
+}
+
+// callBatch sends reqs with send in a span covering the batch, with a child span for each request
+// ended with the error found by errs in the reply.
+func callBatch[T any](ctx context.Context, c *Client, reqs []*jsonrpc2.Request,
+	send func(context.Context, []*jsonrpc2.Request) (T, error), errs func(T) map[string]*jsonrpc2.Error) (T, error) {
+	ctx, batchSpan := c.tracer.Start(ctx, "batch", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(systemKey.String("jsonrpc"), batchSizeKey.Int(len(reqs))))
+
+	spans := make([]trace.Span, len(reqs))
+	sent := make([]*jsonrpc2.Request, len(reqs))
+	for i, req := range reqs {
+		var reqCtx context.Context
+		reqCtx, spans[i] = c.start(ctx, req, req.IsNotification())
+		sent[i] = c.injectMeta(reqCtx, req)
+	}
+
+	reply, err := send(c.injectHeader(ctx), sent)
+	replyErrs := errs(reply)
+	for i, req := range reqs {
+		var rpcErr *jsonrpc2.Error
+		if !req.IsNotification() {
+			rpcErr = replyErrs[idKey(req.ID)]
+		}
+		endSpan(spans[i], rpcErr, err)
+	}
+	endSpan(batchSpan, nil, err)
+	return reply, err
+}
+
+// Notify sends a notification through the client in a span named after its method.
+func (c *Client) Notify(ctx context.Context, req *jsonrpc2.Request) error {
+	ctx, span := c.start(ctx, req, true)
+	err := c.next.Notify(c.injectHeader(ctx), c.injectMeta(ctx, req))
+	endSpan(span, nil, err)
+	return err
+}
+
+// Stats returns the health of the connections of the underlying client, if it reports it as a [jsonrpc2.TCPClient] does,
+// or zero stats otherwise.
+func (c *Client) Stats() jsonrpc2.PoolStats {
+	if s, ok := c.next.(interface{ Stats() jsonrpc2.PoolStats }); ok {
+		return s.Stats()
+	}
+	return jsonrpc2.PoolStats{}
+}
+
+// Close closes the underlying client, if it can be closed.
+func (c *Client) Close() error {
+	if closer, ok := c.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// start starts the client span of req, sent as a notification if notification is set.
+func (c *Client) start(ctx context.Context, req *jsonrpc2.Request, notification bool) (context.Context, trace.Span) {
+	attrs := requestAttributes(req, req.Method, notification)
+	return c.tracer.Start(ctx, req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// injectHeader returns a copy of ctx carrying its trace context in the HTTP headers sent by [jsonrpc2.HTTPClient].
+// Other clients ignore these headers.
+func (c *Client) injectHeader(ctx context.Context) context.Context {
+	header := http.Header{}
+	c.cfg.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+	if len(header) == 0 {
+		return ctx
+	}
+	return jsonrpc2.ContextWithHTTPHeader(ctx, header)
+}
+
+// injectMeta returns req carrying the trace context of ctx in its params, if enabled.
+func (c *Client) injectMeta(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Request {
+	if !c.meta {
+		return req
+	}
+	carrier := propagation.MapCarrier{}
+	c.cfg.propagator.Inject(ctx, carrier)
+	return injectMeta(req, carrier)
+}
+
+// batchErrors returns the errors of the responses to a batch by ID key, see idKey.
+func batchErrors(reply any) map[string]*jsonrpc2.Error {
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return nil
+	}
+	var resps []struct {
+		ID    json.RawMessage `json:"id"`
+		Error *jsonrpc2.Error `json:"error"`
+	}
+	if json.Unmarshal(data, &resps) != nil {
+		return nil
+	}
+	errs := make(map[string]*jsonrpc2.Error, len(resps))
+	for _, resp := range resps {
+		if resp.Error != nil {
+			errs[compact(resp.ID)] = resp.Error
+		}
+	}
+	return errs
+}
+
+// responseErrors returns the errors of resps by ID key, see idKey.
+func responseErrors(resps []*jsonrpc2.Response) map[string]*jsonrpc2.Error {
+	errs := make(map[string]*jsonrpc2.Error, len(resps))
+	for _, resp := range resps {
+		if resp != nil && resp.Error != nil {
+			errs[idKey(resp.ID)] = resp.Error
+		}
+	}
+	return errs
+}
+
+// idKey returns the key identifying a request ID, matching the compacted encoding of the ID of its response.
+func idKey(id any) string {
+	data, err := json.Marshal(id)
+	if err != nil {
+		return ""
+	}
+	return compact(data)
+}
+
+// compact returns data without insignificant space.
+func compact(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,187 @@
+// Package jsonrpc2otel traces JSON-RPC 2.0 clients and servers built with go-jsonrpc2 with OpenTelemetry.
+//
+// Servers create a span around the handling of each request, including each request of a batch, with [Middleware]:
+//
+//	server := jsonrpc2.NewHTTPServer(":8080", "/rpc", jsonrpc2.WithMiddleware(jsonrpc2otel.Middleware()))
+//
+// Clients create a span around each call and notification when wrapped by [NewClient]:
+//
+//	client := jsonrpc2otel.NewClient(jsonrpc2.NewHTTPClient(url, nil))
+//
+// Spans follow the OpenTelemetry semantic conventions for RPC, with the attributes rpc.system set to "jsonrpc",
+// rpc.method, rpc.jsonrpc.version, rpc.jsonrpc.request_id and, for failed requests, rpc.jsonrpc.error_code
+// and rpc.jsonrpc.error_message. Server spans of requests for methods the server does not know are named "jsonrpc"
+// and have no rpc.method, so that clients cannot make up span names.
+//
+// The trace context is propagated in the headers of HTTP requests, and in the "_meta" member of the params of requests
+// sent over other transports, such as TCP, WebSocket and stdio, as in
+//
+//	{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2,"_meta":{"traceparent":"00-..."}},"id":1}
+//
+// Requests whose params are arrays cannot carry the member and are sent without trace context over those transports.
+package jsonrpc2otel
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer spans are created with.
+const instrumentationName = "github.com/mi-wada/go-jsonrpc2/jsonrpc2otel"
+
+// metaKey is the member of params carrying the trace context of requests not sent over HTTP.
+const metaKey = "_meta"
+
+// Attribute keys of the OpenTelemetry semantic conventions for JSON-RPC.
+const (
+	systemKey       = attribute.Key("rpc.system")
+	methodKey       = attribute.Key("rpc.method")
+	versionKey      = attribute.Key("rpc.jsonrpc.version")
+	requestIDKey    = attribute.Key("rpc.jsonrpc.request_id")
+	errorCodeKey    = attribute.Key("rpc.jsonrpc.error_code")
+	errorMessageKey = attribute.Key("rpc.jsonrpc.error_message")
+	peerAddressKey  = attribute.Key("network.peer.address")
+	batchSizeKey    = attribute.Key("rpc.jsonrpc.batch_size")
+)
+
+// Option defines a function type for setting optional fields of the instrumentation.
+type Option func(*config)
+
+// config holds the settings configured by [Option].
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	meta           *bool // Whether clients propagate the trace context in params, if set.
+}
+
+// newConfig applies opts on top of the global tracer provider and propagator.
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.tracerProvider == nil {
+		c.tracerProvider = otel.GetTracerProvider()
+	}
+	if c.propagator == nil {
+		c.propagator = otel.GetTextMapPropagator()
+	}
+	return c
+}
+
+// tracer returns the tracer spans are created with.
+func (c *config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+// WithTracerProvider sets the provider of the tracer spans are created with. It defaults to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithPropagators sets the propagator the trace context is injected and extracted with.
+// It defaults to the global propagator, see [otel.SetTextMapPropagator].
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithMetaPropagation sets whether a client wrapped by [NewClient] injects the trace context in the "_meta" member
+// of params. By default, it does unless it wraps an [jsonrpc2.HTTPClient], whose requests carry it in their headers.
+func WithMetaPropagation(enabled bool) Option {
+	return func(c *config) {
+		c.meta = &enabled
+	}
+}
+
+// unknownSpanName is the name of the server spans of requests for methods the server does not know.
+const unknownSpanName = "jsonrpc"
+
+// requestAttributes returns the attributes describing req, with rpc.method set to method unless it is empty,
+// and its ID unless it is sent as a notification.
+func requestAttributes(req *jsonrpc2.Request, method string, notification bool) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{systemKey.String("jsonrpc")}
+	if method != "" {
+		attrs = append(attrs, methodKey.String(method))
+	}
+	attrs = append(attrs, versionKey.String(req.JSONRPC))
+	if !notification {
+		attrs = append(attrs, requestIDKey.String(fmt.Sprint(req.ID)))
+	}
+	return attrs
+}
+
+// endSpan records the outcome of a request on span and ends it.
+func endSpan(span trace.Span, rpcErr *jsonrpc2.Error, err error) {
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case rpcErr != nil:
+		span.SetAttributes(errorCodeKey.Int(int(rpcErr.Code)), errorMessageKey.String(rpcErr.Message))
+		span.SetStatus(codes.Error, rpcErr.Message)
+	}
+	span.End()
+}
+
+// extractMeta returns the trace context carried in the "_meta" member of params, if any.
+func extractMeta(params json.RawMessage) propagation.MapCarrier {
+	var envelope struct {
+		Meta map[string]any `json:"_meta"`
+	}
+	if len(params) == 0 || params[0] != '{' || json.Unmarshal(params, &envelope) != nil {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	for k, v := range envelope.Meta {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+	return carrier
+}
+
+// injectMeta returns a copy of req carrying carrier in the "_meta" member of its params, keeping the other members
+// of an existing "_meta". It returns req itself if its params are not an object.
+func injectMeta(req *jsonrpc2.Request, carrier propagation.MapCarrier) *jsonrpc2.Request {
+	if len(carrier) == 0 {
+		return req
+	}
+	params := map[string]json.RawMessage{}
+	if len(req.Params) > 0 {
+		if req.Params[0] != '{' || json.Unmarshal(req.Params, &params) != nil {
+			return req
+		}
+	}
+	meta := map[string]any{}
+	if raw, ok := params[metaKey]; ok {
+		if json.Unmarshal(raw, &meta) != nil {
+			return req
+		}
+	}
+	for k, v := range carrier {
+		meta[k] = v
+	}
+
+	var err error
+	if params[metaKey], err = json.Marshal(meta); err != nil {
+		return req
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return req
+	}
+	injected := *req
+	injected.Params = data
+	return &injected
+}
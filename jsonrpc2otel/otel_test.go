@@ -0,0 +1,282 @@
+package jsonrpc2otel_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"github.com/mi-wada/go-jsonrpc2/jsonrpc2otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newExporter returns an exporter recording the spans of the options it returns.
+func newExporter(t *testing.T) (*tracetest.InMemoryExporter, []jsonrpc2otel.Option) {
+	t.Helper()
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return exp, []jsonrpc2otel.Option{
+		jsonrpc2otel.WithTracerProvider(tp),
+		jsonrpc2otel.WithPropagators(propagation.TraceContext{}),
+	}
+}
+
+type addParams struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+// register registers the test methods on s.
+func register(s jsonrpc2.Server) {
+	jsonrpc2.RegisterFunc(s, "add", func(ctx context.Context, p addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+}
+
+// serveStream serves a stream server traced with opts on a pipe listener until the test ends, and returns a client of it.
+func serveStream(t *testing.T, opts []jsonrpc2otel.Option) *jsonrpc2.TCPClient {
+	t.Helper()
+	s := jsonrpc2.NewTCPServer("", jsonrpc2.WithMiddleware(jsonrpc2otel.Middleware(opts...)))
+	register(s)
+	ln := jsonrpc2.NewPipeListener()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx, ln)
+	}()
+	client := jsonrpc2.NewTCPClientWithDialer(ln.Dial)
+	t.Cleanup(func() {
+		client.Close()
+		cancel()
+		<-done
+	})
+	return client
+}
+
+// serveHTTP serves an HTTP server traced with opts until the test ends, and returns a client of it.
+func serveHTTP(t *testing.T, opts []jsonrpc2otel.Option) *jsonrpc2.HTTPClient {
+	t.Helper()
+	s := jsonrpc2.NewHTTPServer("", "/", jsonrpc2.WithMiddleware(jsonrpc2otel.Middleware(opts...)))
+	register(s)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return jsonrpc2.NewHTTPClient(ts.URL, nil)
+}
+
+func newRequest(t *testing.T, method string, id any) *jsonrpc2.Request {
+	t.Helper()
+	opts := []jsonrpc2.NewRequestOption{jsonrpc2.WithParams(addParams{A: 1, B: 2})}
+	if id != nil {
+		opts = append(opts, jsonrpc2.WithID(id))
+	}
+	req, err := jsonrpc2.NewRequest(method, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// attributes returns the attributes of span by key.
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// findSpan returns the span named name of kind, failing the test if there is none.
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string, kind trace.SpanKind) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name && span.SpanKind == kind {
+			return span
+		}
+	}
+	t.Fatalf("no %v span named %q in %d spans", kind, name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		params     string // Raw params replacing valid ones, if set.
+		wantName   string
+		wantMethod string // Empty if rpc.method must not be set.
+		wantCode   int64
+	}{
+		{name: "known method", method: "add", wantName: "add", wantMethod: "add"},
+		{name: "error", method: "add", params: `{"a":"x"}`, wantName: "add", wantMethod: "add", wantCode: int64(jsonrpc2.InvalidParams)},
+		{name: "unknown method", method: "made/up", wantName: "jsonrpc", wantCode: int64(jsonrpc2.MethodNotFound)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, opts := newExporter(t)
+			client := serveStream(t, opts)
+
+			req := newRequest(t, tt.method, 7)
+			if tt.params != "" {
+				req.Params = []byte(tt.params)
+			}
+			if _, err := client.Call(testContext(t), req); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+
+			spans := exp.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := findSpan(t, spans, tt.wantName, trace.SpanKindServer)
+			attrs := attributes(span)
+			if got := attrs["rpc.system"].AsString(); got != "jsonrpc" {
+				t.Errorf("got rpc.system %q, want %q", got, "jsonrpc")
+			}
+			if got, ok := attrs["rpc.method"]; ok != (tt.wantMethod != "") || got.AsString() != tt.wantMethod {
+				t.Errorf("got rpc.method %q (set: %v), want %q", got.AsString(), ok, tt.wantMethod)
+			}
+			if got := attrs["rpc.jsonrpc.request_id"].AsString(); got != "7" {
+				t.Errorf("got rpc.jsonrpc.request_id %q, want %q", got, "7")
+			}
+			if got := attrs["rpc.jsonrpc.error_code"].AsInt64(); got != tt.wantCode {
+				t.Errorf("got rpc.jsonrpc.error_code %d, want %d", got, tt.wantCode)
+			}
+			if wantStatus := tt.wantCode != 0; (span.Status.Code == codes.Error) != wantStatus {
+				t.Errorf("got status %v, want error: %v", span.Status.Code, wantStatus)
+			}
+		})
+	}
+}
+
+func TestClientPropagation(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(t *testing.T, opts []jsonrpc2otel.Option) jsonrpc2.Client
+	}{
+		{
+			name: "stream in params",
+			serve: func(t *testing.T, opts []jsonrpc2otel.Option) jsonrpc2.Client {
+				return serveStream(t, opts)
+			},
+		},
+		{
+			name: "HTTP in headers",
+			serve: func(t *testing.T, opts []jsonrpc2otel.Option) jsonrpc2.Client {
+				return serveHTTP(t, opts)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, opts := newExporter(t)
+			client := jsonrpc2otel.NewClient(tt.serve(t, opts), opts...)
+
+			resp, err := client.Call(testContext(t), newRequest(t, "add", 1))
+			if err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			var sum int
+			if err := resp.DecodeResult(&sum); err != nil || sum != 3 {
+				t.Fatalf("got result %d (%v), want 3", sum, err)
+			}
+
+			spans := exp.GetSpans()
+			clientSpan := findSpan(t, spans, "add", trace.SpanKindClient)
+			serverSpan := findSpan(t, spans, "add", trace.SpanKindServer)
+			if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+				t.Errorf("got server span parent %v, want client span %v", serverSpan.Parent.SpanID(), clientSpan.SpanContext.SpanID())
+			}
+			if serverSpan.SpanContext.TraceID() != clientSpan.SpanContext.TraceID() {
+				t.Errorf("got server trace %v, want client trace %v", serverSpan.SpanContext.TraceID(), clientSpan.SpanContext.TraceID())
+			}
+		})
+	}
+}
+
+func TestClientNotify(t *testing.T) {
+	exp, opts := newExporter(t)
+	client := jsonrpc2otel.NewClient(serveHTTP(t, opts), opts...)
+	if err := client.Notify(testContext(t), newRequest(t, "add", nil)); err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+	span := findSpan(t, exp.GetSpans(), "add", trace.SpanKindClient)
+	if _, ok := attributes(span)["rpc.jsonrpc.request_id"]; ok {
+		t.Error("notification span has a request ID")
+	}
+}
+
+func TestClientBatch(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(ctx context.Context, c *jsonrpc2otel.Client, reqs []*jsonrpc2.Request) error
+	}{
+		{
+			name: "CallBatch",
+			call: func(ctx context.Context, c *jsonrpc2otel.Client, reqs []*jsonrpc2.Request) error {
+				_, err := c.CallBatch(ctx, reqs)
+				return err
+			},
+		},
+		{
+			name: "CallBatchResponses",
+			call: func(ctx context.Context, c *jsonrpc2otel.Client, reqs []*jsonrpc2.Request) error {
+				_, err := jsonrpc2.CallBatchResponses(ctx, c, reqs)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp, opts := newExporter(t)
+			client := jsonrpc2otel.NewClient(serveStream(t, opts), opts...)
+
+			reqs := []*jsonrpc2.Request{newRequest(t, "add", 1), newRequest(t, "missing", 2)}
+			if err := tt.call(testContext(t), client, reqs); err != nil {
+				t.Fatalf("batch failed: %v", err)
+			}
+
+			spans := exp.GetSpans()
+			batch := findSpan(t, spans, "batch", trace.SpanKindClient)
+			if got := attributes(batch)["rpc.jsonrpc.batch_size"].AsInt64(); got != 2 {
+				t.Errorf("got rpc.jsonrpc.batch_size %d, want 2", got)
+			}
+			for _, want := range []struct {
+				name string
+				code int64
+			}{
+				{name: "add"},
+				{name: "missing", code: int64(jsonrpc2.MethodNotFound)},
+			} {
+				span := findSpan(t, spans, want.name, trace.SpanKindClient)
+				if span.Parent.SpanID() != batch.SpanContext.SpanID() {
+					t.Errorf("span %q is not a child of the batch span", want.name)
+				}
+				if got := attributes(span)["rpc.jsonrpc.error_code"].AsInt64(); got != want.code {
+					t.Errorf("got rpc.jsonrpc.error_code %d for %q, want %d", got, want.name, want.code)
+				}
+			}
+			// The server spans of the batch are children of the client spans of its requests.
+			for _, names := range [][2]string{{"add", "add"}, {"missing", "jsonrpc"}} {
+				clientSpan := findSpan(t, spans, names[0], trace.SpanKindClient)
+				serverSpan := findSpan(t, spans, names[1], trace.SpanKindServer)
+				if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+					t.Errorf("server span %q is not a child of client span %q", names[1], names[0])
+				}
+			}
+		})
+	}
+}
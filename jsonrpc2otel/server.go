@@ -0,0 +1,47 @@
+package jsonrpc2otel
+
+import (
+	"context"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns a [jsonrpc2.Middleware] creating a server span around the handling of each request.
+// The span is the child of the trace context carried by the "_meta" member of the params if any,
+// or else by the headers of the HTTP request or WebSocket opening handshake the request was received with.
+// Pass it to [jsonrpc2.WithMiddleware], first so that the span covers the other middleware.
+// Spans are named after the method of the request, or "jsonrpc" if the server does not know the method, see [jsonrpc2.KnownMethod].
+func Middleware(opts ...Option) jsonrpc2.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.tracer()
+	return func(next jsonrpc2.Handler) jsonrpc2.Handler {
+		return func(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Response {
+			if header := jsonrpc2.RequestHeaderFromContext(ctx); header != nil {
+				ctx = cfg.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+			}
+			if carrier := extractMeta(req.Params); len(carrier) > 0 {
+				ctx = cfg.propagator.Extract(ctx, carrier)
+			}
+
+			name, method := req.Method, req.Method
+			if !jsonrpc2.KnownMethod(ctx, method) {
+				name, method = unknownSpanName, ""
+			}
+			attrs := requestAttributes(req, method, req.IsNotification())
+			if peer, ok := jsonrpc2.PeerFromContext(ctx); ok && peer.RemoteAddr != "" {
+				attrs = append(attrs, peerAddressKey.String(peer.RemoteAddr))
+			}
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+
+			resp := next(ctx, req)
+			var rpcErr *jsonrpc2.Error
+			if resp != nil {
+				rpcErr = resp.Error
+			}
+			endSpan(span, rpcErr, nil)
+			return resp
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package jsonrpc2prom exports the metrics of JSON-RPC 2.0 clients and servers built with go-jsonrpc2 to Prometheus.
+//
+// Servers report the requests they handle with [jsonrpc2.WithMetrics], and clients the calls they make
+// with [jsonrpc2.WithClientMetrics]:
+//
+//	server := jsonrpc2.NewTCPServer(":9000", jsonrpc2.WithMetrics(jsonrpc2prom.NewServerMetrics(prometheus.DefaultRegisterer)))
+//	client := jsonrpc2.NewTCPClientWithDialer(dialer, jsonrpc2.WithClientMetrics(jsonrpc2prom.NewClientMetrics(prometheus.DefaultRegisterer)))
+//
+// The metrics are named jsonrpc2_server_* for servers and jsonrpc2_client_* for clients:
+//
+//	requests_total             counter of requests by method
+//	errors_total               counter of failed requests by method and code, the code being "transport"
+//	                           for calls that failed without response
+//	request_duration_seconds   histogram of the duration of requests by method
+//	requests_in_flight         gauge of the requests being handled or sent, by method
+//	batch_size                 histogram of the number of requests of batches
+//	connections                gauge of the open connections by transport, for servers and clients that keep them
+//
+// Servers report the requests of unknown methods and invalid messages with an empty method.
+package jsonrpc2prom
+
+import (
+	"strconv"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultNamespace is the namespace of the metrics unless set with [WithNamespace].
+const defaultNamespace = "jsonrpc2"
+
+// transportCode is the code label of the errors of calls that failed without response.
+const transportCode = "transport"
+
+// Option defines a function type for setting optional fields of [Metrics].
+type Option func(*config)
+
+// config holds the settings configured by [Option].
+type config struct {
+	namespace       string
+	durationBuckets []float64
+	batchBuckets    []float64
+}
+
+// WithNamespace sets the namespace the metrics are named with. It defaults to "jsonrpc2".
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithDurationBuckets sets the buckets, in seconds, of the request duration histogram.
+// It defaults to [prometheus.DefBuckets].
+func WithDurationBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.durationBuckets = buckets
+	}
+}
+
+// WithBatchBuckets sets the buckets of the batch size histogram. It defaults to 1, 2, 5, 10, 20, 50 and 100.
+func WithBatchBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		c.batchBuckets = buckets
+	}
+}
+
+// Metrics is a [jsonrpc2.Metrics] recording measurements in Prometheus collectors.
+type Metrics struct {
+	requests    *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	inFlight    *prometheus.GaugeVec
+	batchSize   prometheus.Histogram
+	connections *prometheus.GaugeVec
+}
+
+var _ jsonrpc2.Metrics = (*Metrics)(nil)
+
+// NewServerMetrics creates a new [Metrics] for servers, registering its collectors with reg.
+// A nil reg leaves them unregistered, e.g. to register them later as a [prometheus.Collector].
+// It panics if the collectors cannot be registered, e.g. because metrics with the same names already are.
+func NewServerMetrics(reg prometheus.Registerer, opts ...Option) *Metrics {
+	return newMetrics(reg, "server", "handled by the server", opts)
+}
+
+// NewClientMetrics creates a new [Metrics] for clients, registering its collectors with reg, see [NewServerMetrics].
+func NewClientMetrics(reg prometheus.Registerer, opts ...Option) *Metrics {
+	return newMetrics(reg, "client", "sent by the client", opts)
+}
+
+// newMetrics creates the collectors of subsystem, whose requests are described by by.
+func newMetrics(reg prometheus.Registerer, subsystem, by string, opts []Option) *Metrics {
+	c := &config{
+		namespace:       defaultNamespace,
+		durationBuckets: prometheus.DefBuckets,
+		batchBuckets:    []float64{1, 2, 5, 10, 20, 50, 100},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of JSON-RPC requests " + by + ".",
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "errors_total",
+			Help:      "Number of JSON-RPC requests " + by + " that failed, by error code.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the JSON-RPC requests " + by + ".",
+			Buckets:   c.durationBuckets,
+		}, []string{"method"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "requests_in_flight",
+			Help:      "Number of JSON-RPC requests being " + by + ".",
+		}, []string{"method"}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "batch_size",
+			Help:      "Number of requests of the JSON-RPC batches " + by + ".",
+			Buckets:   c.batchBuckets,
+		}),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Subsystem: subsystem,
+			Name:      "connections",
+			Help:      "Number of open connections, by transport.",
+		}, []string{"transport"}),
+	}
+	if reg != nil {
+		reg.MustRegister(m)
+	}
+	return m
+}
+
+// Describe implements [prometheus.Collector].
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements [prometheus.Collector].
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// collectors returns the collectors of m.
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.requests, m.errors, m.duration, m.inFlight, m.batchSize, m.connections}
+}
+
+// RequestStarted implements [jsonrpc2.Metrics].
+func (m *Metrics) RequestStarted(method string) {
+	m.inFlight.WithLabelValues(method).Inc()
+}
+
+// RequestFinished implements [jsonrpc2.Metrics].
+func (m *Metrics) RequestFinished(stats jsonrpc2.RequestStats) {
+	m.inFlight.WithLabelValues(stats.Method).Dec()
+	m.requests.WithLabelValues(stats.Method).Inc()
+	m.duration.WithLabelValues(stats.Method).Observe(stats.Duration.Seconds())
+	switch {
+	case stats.Err != nil:
+		m.errors.WithLabelValues(stats.Method, transportCode).Inc()
+	case stats.Code != 0:
+		m.errors.WithLabelValues(stats.Method, strconv.Itoa(int(stats.Code))).Inc()
+	}
+}
+
+// Batch implements [jsonrpc2.Metrics].
+func (m *Metrics) Batch(size int) {
+	m.batchSize.Observe(float64(size))
+}
+
+// ConnOpened implements [jsonrpc2.Metrics].
+func (m *Metrics) ConnOpened(transport string) {
+	m.connections.WithLabelValues(transport).Inc()
+}
+
+// ConnClosed implements [jsonrpc2.Metrics].
+func (m *Metrics) ConnClosed(transport string) {
+	m.connections.WithLabelValues(transport).Dec()
+}
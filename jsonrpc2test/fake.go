@@ -0,0 +1,102 @@
+package jsonrpc2test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+// Reply is a canned reply of a [FakeServer].
+type Reply struct {
+	Result any             // The result of the call, used when Error is nil.
+	Error  *jsonrpc2.Error // The error the call fails with, if any.
+}
+
+// FakeServer is a server replying to each method with a script of canned replies, and recording the requests
+// it receives, e.g.
+//
+//	fake := jsonrpc2test.NewFakeServer(jsonrpc2.NewHTTPServer("", "/rpc"))
+//	fake.On("add", jsonrpc2test.Reply{Error: jsonrpc2.NewError(jsonrpc2.InternalError, "busy")}, jsonrpc2test.Reply{Result: 3})
+//	ts := jsonrpc2test.NewServer(fake)
+//	defer ts.Close()
+//
+// Methods without a script are not found, as for any server.
+type FakeServer struct {
+	Server jsonrpc2.Server // The server the scripts are registered on.
+
+	mu       sync.Mutex
+	scripts  map[string][]Reply
+	requests map[string][]*jsonrpc2.Request
+}
+
+// NewFakeServer creates a new [FakeServer] registering its scripts on srv.
+func NewFakeServer(srv jsonrpc2.Server) *FakeServer {
+	return &FakeServer{
+		Server:   srv,
+		scripts:  make(map[string][]Reply),
+		requests: make(map[string][]*jsonrpc2.Request),
+	}
+}
+
+var _ jsonrpc2.Describer = (*FakeServer)(nil)
+
+// Register registers a handler for a method on the underlying server, alongside the scripted ones.
+func (f *FakeServer) Register(method string, handler jsonrpc2.Handler) {
+	f.Server.Register(method, handler)
+}
+
+// RegisterMethod registers a handler for a method on the underlying server, described by opts if it is a
+// [jsonrpc2.Describer].
+func (f *FakeServer) RegisterMethod(method string, handler jsonrpc2.Handler, opts ...jsonrpc2.RegisterOption) {
+	if d, ok := f.Server.(jsonrpc2.Describer); ok {
+		d.RegisterMethod(method, handler, opts...)
+		return
+	}
+	f.Server.Register(method, handler)
+}
+
+// Run runs the underlying server.
+func (f *FakeServer) Run(ctx context.Context) error {
+	return f.Server.Run(ctx)
+}
+
+// On scripts the replies to method, replacing its previous script. The calls of method are replied to in turn
+// with replies, the last one being repeated once the others are used up. Without replies, calls get a null result.
+func (f *FakeServer) On(method string, replies ...Reply) {
+	f.mu.Lock()
+	f.scripts[method] = replies
+	f.mu.Unlock()
+
+	f.Server.Register(method, func(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Response {
+		reply := f.next(req)
+		if reply.Error != nil {
+			return jsonrpc2.NewResponse(req.ID, jsonrpc2.WithError(*reply.Error))
+		}
+		return jsonrpc2.NewResponse(req.ID, jsonrpc2.WithResult(reply.Result))
+	})
+}
+
+// Requests returns the requests and notifications of method received so far.
+func (f *FakeServer) Requests(method string) []*jsonrpc2.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*jsonrpc2.Request(nil), f.requests[method]...)
+}
+
+// next records req and returns the reply to it.
+func (f *FakeServer) next(req *jsonrpc2.Request) Reply {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[req.Method] = append(f.requests[req.Method], req)
+
+	script := f.scripts[req.Method]
+	if len(script) == 0 {
+		return Reply{}
+	}
+	reply := script[0]
+	if len(script) > 1 {
+		f.scripts[req.Method] = script[1:]
+	}
+	return reply
+}
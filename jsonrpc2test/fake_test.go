@@ -0,0 +1,71 @@
+package jsonrpc2test_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"github.com/mi-wada/go-jsonrpc2/jsonrpc2test"
+)
+
+func TestFakeServer(t *testing.T) {
+	fake := jsonrpc2test.NewFakeServer(jsonrpc2.NewHTTPServer("", "/rpc"))
+	fake.On("add",
+		jsonrpc2test.Reply{Error: jsonrpc2.NewError(jsonrpc2.InternalError, "busy")},
+		jsonrpc2test.Reply{Result: 3},
+	)
+	fake.On("ping")
+	fake.Register("echo", func(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Response {
+		return jsonrpc2.NewResponse(req.ID, jsonrpc2.WithResult(req.Params))
+	})
+	ts := jsonrpc2test.NewServer(fake)
+	defer ts.Close()
+
+	ctx := testContext(t)
+	// The last reply of a script is repeated, scripts without replies return null,
+	// and methods without a script are not found.
+	for i, req := range []*jsonrpc2.Request{
+		newRequest(t, "add", []int{1, 2}, 1),
+		newRequest(t, "add", []int{1, 2}, 2),
+		newRequest(t, "add", []int{1, 2}, 3),
+		newRequest(t, "ping", nil, 4),
+		newRequest(t, "echo", []string{"hello"}, 5),
+		newRequest(t, "subtract", []int{42, 23}, 6),
+	} {
+		if _, err := ts.Client.Call(ctx, req); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if err := ts.Client.Notify(ctx, newRequest(t, "add", []int{3, 4}, nil)); err != nil {
+		t.Fatalf("notify failed: %v", err)
+	}
+	jsonrpc2test.AssertGolden(t, "testdata/fake.golden", ts.Recorder.Transcript())
+
+	requests := fake.Requests("add")
+	if len(requests) != 4 {
+		t.Fatalf("got %d requests of add, want 4", len(requests))
+	}
+	if !requests[3].IsNotification() {
+		t.Errorf("got request %+v, want the notification", requests[3])
+	}
+	if got := fake.Requests("echo"); len(got) != 0 {
+		t.Errorf("got %d requests of echo, want none as it is not scripted", len(got))
+	}
+}
+
+func TestFakeServerOnReplacesScript(t *testing.T) {
+	fake := jsonrpc2test.NewFakeServer(jsonrpc2.NewTCPServer(""))
+	fake.On("answer", jsonrpc2test.Reply{Result: 1})
+	fake.On("answer", jsonrpc2test.Reply{Result: 42})
+	ts := jsonrpc2test.NewServer(fake)
+	defer ts.Close()
+
+	resp, err := ts.Client.Call(testContext(t), newRequest(t, "answer", nil, 1))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	var got int
+	if err := resp.DecodeResult(&got); err != nil || got != 42 {
+		t.Errorf("got result %d (%v), want 42", got, err)
+	}
+}
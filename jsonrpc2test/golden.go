@@ -0,0 +1,77 @@
+package jsonrpc2test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes [AssertGolden] rewrite golden files rather than compare with them
+// when set to a true value, e.g.
+//
+//	JSONRPC2TEST_UPDATE=1 go test ./...
+//
+// An environment variable is used rather than a flag so that importing this package leaves the flags of tests unchanged.
+const UpdateEnv = "JSONRPC2TEST_UPDATE"
+
+// update reports whether golden files must be rewritten, see [UpdateEnv].
+func update() bool {
+	v, _ := strconv.ParseBool(os.Getenv(UpdateEnv))
+	return v
+}
+
+// AssertGolden compares got, typically a [Recorder.Transcript], with the content of the golden file at path,
+// failing t with a diff if they differ. Line endings are ignored, so that golden files survive checkouts converting them.
+// Running the tests with [UpdateEnv] set writes got to the file instead, creating its directory if needed.
+func AssertGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if update() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create the directory of %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s, set %s=1 to create it: %v", path, UpdateEnv, err)
+	}
+	got, want = normalizeNewlines(got), normalizeNewlines(want)
+	if !bytes.Equal(got, want) {
+		t.Errorf("transcript differs from %s, set %s=1 to accept it:\n%s", path, UpdateEnv, diffLines(want, got))
+	}
+}
+
+// normalizeNewlines replaces the CRLF line endings of data with LF.
+func normalizeNewlines(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// diffLines returns the lines of want and got from the first that differs, prefixed with "-" and "+" respectively.
+func diffLines(want, got []byte) string {
+	wantLines := bytes.Split(want, []byte("\n"))
+	gotLines := bytes.Split(got, []byte("\n"))
+	i := 0
+	for i < len(wantLines) && i < len(gotLines) && bytes.Equal(wantLines[i], gotLines[i]) {
+		i++
+	}
+
+	var buf bytes.Buffer
+	for _, line := range wantLines[i:] {
+		buf.WriteString("- ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	for _, line := range gotLines[i:] {
+		buf.WriteString("+ ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
@@ -0,0 +1,126 @@
+package jsonrpc2test_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mi-wada/go-jsonrpc2/jsonrpc2test"
+)
+
+// recordingTB is a [testing.TB] recording the failures reported to it instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertGolden(t *testing.T) {
+	const transcript = "--> {\"jsonrpc\":\"2.0\",\"method\":\"ping\",\"id\":1}\n<-- {\"jsonrpc\":\"2.0\",\"result\":null,\"id\":1}\n"
+	tests := []struct {
+		name        string
+		golden      *string // The content of the golden file, or nil if there is none.
+		got         string
+		wantFailure []string // Substrings of the failure reported, if any.
+	}{
+		{
+			name:   "same",
+			golden: ptr(transcript),
+			got:    transcript,
+		},
+		{
+			name:   "CRLF line endings",
+			golden: ptr(strings.ReplaceAll(transcript, "\n", "\r\n")),
+			got:    transcript,
+		},
+		{
+			name:        "different",
+			golden:      ptr(transcript),
+			got:         strings.Replace(transcript, "null", "true", 1),
+			wantFailure: []string{"transcript differs", "- <-- {\"jsonrpc\":\"2.0\",\"result\":null", "+ <-- {\"jsonrpc\":\"2.0\",\"result\":true", jsonrpc2test.UpdateEnv},
+		},
+		{
+			name:        "missing",
+			got:         transcript,
+			wantFailure: []string{"failed to read", jsonrpc2test.UpdateEnv + "=1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(jsonrpc2test.UpdateEnv, "")
+			path := filepath.Join(t.TempDir(), "test.golden")
+			if tt.golden != nil {
+				if err := os.WriteFile(path, []byte(*tt.golden), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tb := &recordingTB{TB: t}
+			jsonrpc2test.AssertGolden(tb, path, []byte(tt.got))
+			if len(tt.wantFailure) == 0 {
+				if len(tb.failures) > 0 {
+					t.Errorf("got failures %q, want none", tb.failures)
+				}
+				return
+			}
+			if len(tb.failures) == 0 {
+				t.Fatal("got no failure")
+			}
+			for _, want := range tt.wantFailure {
+				if !strings.Contains(tb.failures[0], want) {
+					t.Errorf("got failure %q, want it to contain %q", tb.failures[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		update bool
+	}{
+		{name: "1", value: "1", update: true},
+		{name: "true", value: "true", update: true},
+		{name: "false", value: "false"},
+		{name: "invalid", value: "yes please"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(jsonrpc2test.UpdateEnv, tt.value)
+			path := filepath.Join(t.TempDir(), "nested", "test.golden")
+
+			tb := &recordingTB{TB: t}
+			jsonrpc2test.AssertGolden(tb, path, []byte("--> {}\n"))
+			data, err := os.ReadFile(path)
+			if !tt.update {
+				if err == nil {
+					t.Errorf("golden file written with %s=%q", jsonrpc2test.UpdateEnv, tt.value)
+				}
+				return
+			}
+			if len(tb.failures) > 0 {
+				t.Fatalf("got failures %q, want none", tb.failures)
+			}
+			if err != nil || string(data) != "--> {}\n" {
+				t.Errorf("got golden file %q (%v), want %q", data, err, "--> {}\n")
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
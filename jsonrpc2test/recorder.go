@@ -0,0 +1,138 @@
+package jsonrpc2test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+// Exchange is a message sent by a client and the reply it received, as JSON.
+type Exchange struct {
+	Request  json.RawMessage // The request, notification or batch sent.
+	Response json.RawMessage // The response or batch of responses received, or nil for a notification or a failed call.
+	Err      error           // The error the call failed with, if any.
+}
+
+// Recorder is a [jsonrpc2.Client] recording the exchanges made through another client, e.g.
+//
+//	rec := jsonrpc2test.NewRecorder(client)
+//	rec.Call(ctx, req)
+//	jsonrpc2test.AssertGolden(t, "testdata/call.golden", rec.Transcript())
+type Recorder struct {
+	client jsonrpc2.Client
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder creates a new [Recorder] making calls through client.
+func NewRecorder(client jsonrpc2.Client) *Recorder {
+	return &Recorder{client: client}
+}
+
+var _ jsonrpc2.Client = (*Recorder)(nil)
+
+// Call sends a request through the client and records the exchange.
+func (r *Recorder) Call(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	resp, err := r.client.Call(ctx, req)
+	var reply any
+	if resp != nil {
+		reply = rawResponse(resp)
+	}
+	r.record(req, reply, err)
+	return resp, err
+}
+
+// CallBatch sends requests through the client and records the exchange.
+func (r *Recorder) CallBatch(ctx context.Context, reqs []*jsonrpc2.Request) (any, error) {
+	resps, err := r.client.CallBatch(ctx, reqs)
+	r.record(reqs, resps, err)
+	return resps, err
+}
+
+// Notify sends a notification through the client and records the exchange.
+func (r *Recorder) Notify(ctx context.Context, req *jsonrpc2.Request) error {
+	err := r.client.Notify(ctx, req)
+	r.record(notification(req), nil, err)
+	return err
+}
+
+// Exchanges returns the exchanges recorded so far, in the order they completed.
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// Reset forgets the exchanges recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = nil
+}
+
+// Transcript returns the exchanges recorded so far in the notation of the JSON-RPC 2.0 specification's examples,
+// a "-->" line for each message sent and a "<--" line for each reply, e.g.
+//
+//	--> {"jsonrpc":"2.0","method":"subtract","params":[42,23],"id":1}
+//	<-- {"jsonrpc":"2.0","result":19,"id":1}
+//
+// Failed calls are followed by a "<-- error:" line. The transcript is suitable for [AssertGolden].
+func (r *Recorder) Transcript() []byte {
+	var buf bytes.Buffer
+	for _, e := range r.Exchanges() {
+		fmt.Fprintf(&buf, "--> %s\n", e.Request)
+		switch {
+		case e.Err != nil:
+			fmt.Fprintf(&buf, "<-- error: %v\n", e.Err)
+		case e.Response != nil:
+			fmt.Fprintf(&buf, "<-- %s\n", e.Response)
+		}
+	}
+	return buf.Bytes()
+}
+
+// record appends an exchange of the message sent and the reply received.
+func (r *Recorder) record(sent, reply any, err error) {
+	e := Exchange{Err: err}
+	e.Request = marshal(sent)
+	if reply != nil {
+		e.Response = marshal(reply)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, e)
+}
+
+// notification returns req as sent by [jsonrpc2.Client.Notify], without "id" member.
+func notification(req *jsonrpc2.Request) any {
+	return struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+	}{req.JSONRPC, req.Method, req.Params}
+}
+
+// rawResponse returns resp with its result as received, if known, so that it is recorded exactly.
+func rawResponse(resp *jsonrpc2.Response) *jsonrpc2.Response {
+	if resp.RawResult() == nil {
+		return resp
+	}
+	raw := *resp
+	raw.Result = resp.RawResult()
+	return &raw
+}
+
+// marshal returns the encoding of v, or a JSON string describing why it cannot be encoded.
+func marshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("failed to marshal: %v", err))
+	}
+	return data
+}
@@ -0,0 +1,66 @@
+package jsonrpc2test_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"github.com/mi-wada/go-jsonrpc2/jsonrpc2test"
+)
+
+// failingClient is a [jsonrpc2.Client] whose calls all fail with err.
+type failingClient struct {
+	err error
+}
+
+func (c failingClient) Call(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+	return nil, c.err
+}
+
+func (c failingClient) CallBatch(ctx context.Context, reqs []*jsonrpc2.Request) (any, error) {
+	return nil, c.err
+}
+
+func (c failingClient) Notify(ctx context.Context, req *jsonrpc2.Request) error {
+	return c.err
+}
+
+func TestRecorderFailures(t *testing.T) {
+	errDown := errors.New("server down")
+	rec := jsonrpc2test.NewRecorder(failingClient{errDown})
+	ctx := testContext(t)
+
+	if _, err := rec.Call(ctx, newRequest(t, "subtract", []int{42, 23}, 1)); !errors.Is(err, errDown) {
+		t.Errorf("got error %v, want %v", err, errDown)
+	}
+	if err := rec.Notify(ctx, newRequest(t, "update", []int{1}, nil)); !errors.Is(err, errDown) {
+		t.Errorf("got error %v, want %v", err, errDown)
+	}
+	if _, err := rec.CallBatch(ctx, []*jsonrpc2.Request{newRequest(t, "subtract", []int{1, 1}, 2)}); !errors.Is(err, errDown) {
+		t.Errorf("got error %v, want %v", err, errDown)
+	}
+
+	exchanges := rec.Exchanges()
+	if len(exchanges) != 3 {
+		t.Fatalf("got %d exchanges, want 3", len(exchanges))
+	}
+	for _, e := range exchanges {
+		if !errors.Is(e.Err, errDown) || e.Response != nil {
+			t.Errorf("got exchange %+v, want a failure without response", e)
+		}
+	}
+	jsonrpc2test.AssertGolden(t, "testdata/failures.golden", rec.Transcript())
+}
+
+func TestRecorderReset(t *testing.T) {
+	rec := jsonrpc2test.NewRecorder(failingClient{errors.New("server down")})
+	rec.Call(testContext(t), newRequest(t, "subtract", []int{42, 23}, 1))
+	rec.Reset()
+	if got := rec.Exchanges(); len(got) != 0 {
+		t.Errorf("got %d exchanges after Reset, want none", len(got))
+	}
+	if got := rec.Transcript(); len(got) != 0 {
+		t.Errorf("got transcript %q after Reset, want an empty one", got)
+	}
+}
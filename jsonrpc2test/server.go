@@ -0,0 +1,101 @@
+// Package jsonrpc2test provides utilities for testing JSON-RPC 2.0 servers and clients built with go-jsonrpc2,
+// in the manner of [net/http/httptest].
+package jsonrpc2test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+// Server is a JSON-RPC 2.0 server listening on an ephemeral loopback port, with a client connected to it.
+type Server struct {
+	URL      string           // The URL of the server, e.g. "http://127.0.0.1:50000/rpc" or "tcp://127.0.0.1:50000".
+	Client   jsonrpc2.Client  // A client connected to the server, recording its exchanges in Recorder.
+	Recorder *Recorder        // The exchanges made with Client.
+	Server   jsonrpc2.Server  // The server being served.
+	listener net.Listener     // The listener of a stream server.
+	http     *httptest.Server // The HTTP server of an HTTP or WebSocket server.
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer starts serving srv, a *[jsonrpc2.HTTPServer], *[jsonrpc2.WebSocketServer] or *[jsonrpc2.StreamServer],
+// or a *[FakeServer] of one, on an ephemeral loopback port and connects a client to it with opts. The caller should call Close when finished.
+// Stream servers requiring TLS are not supported. It panics if the server cannot be started.
+func NewServer(srv jsonrpc2.Server, opts ...jsonrpc2.ClientOption) *Server {
+	if fake, ok := srv.(*FakeServer); ok {
+		srv = fake.Server
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{Server: srv, cancel: cancel}
+
+	var client jsonrpc2.Client
+	switch srv := srv.(type) {
+	case *jsonrpc2.HTTPServer:
+		s.http = httptest.NewServer(srv)
+		s.URL = s.http.URL + srv.Path()
+		client = jsonrpc2.NewHTTPClient(s.URL, s.http.Client(), opts...)
+	case *jsonrpc2.WebSocketServer:
+		mux := http.NewServeMux()
+		mux.Handle(srv.Path(), srv)
+		s.http = httptest.NewServer(mux)
+		s.URL = "ws" + strings.TrimPrefix(s.http.URL, "http") + srv.Path()
+		ws, err := jsonrpc2.DialWebSocket(ctx, s.URL, opts...)
+		if err != nil {
+			s.Close()
+			panic(fmt.Sprintf("jsonrpc2test: failed to connect to %s: %v", s.URL, err))
+		}
+		client = ws
+	case *jsonrpc2.StreamServer:
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			cancel()
+			panic(fmt.Sprintf("jsonrpc2test: failed to listen on a port: %v", err))
+		}
+		s.listener = listener
+		s.URL = "tcp://" + listener.Addr().String()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			srv.Serve(ctx, listener)
+		}()
+		client = jsonrpc2.NewTCPClientWithDialer(jsonrpc2.TCPDialer(listener.Addr().String()), opts...)
+	default:
+		cancel()
+		panic(fmt.Sprintf("jsonrpc2test: unsupported server type %T", srv))
+	}
+
+	s.Recorder = NewRecorder(client)
+	s.Client = s.Recorder
+	return s
+}
+
+// Addr returns the address the server listens on, e.g. "127.0.0.1:50000".
+func (s *Server) Addr() string {
+	if s.http != nil {
+		return s.http.Listener.Addr().String()
+	}
+	return s.listener.Addr().String()
+}
+
+// Close closes the client and shuts down the server, waiting for the connections to be served.
+func (s *Server) Close() {
+	if s.Recorder != nil {
+		if c, ok := s.Recorder.client.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	s.cancel()
+	if s.http != nil {
+		s.http.Close()
+	}
+	s.wg.Wait()
+}
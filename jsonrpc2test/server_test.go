@@ -0,0 +1,130 @@
+package jsonrpc2test_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mi-wada/go-jsonrpc2"
+	"github.com/mi-wada/go-jsonrpc2/jsonrpc2test"
+)
+
+type subtractParams struct {
+	Minuend    int `json:"minuend"`
+	Subtrahend int `json:"subtrahend"`
+}
+
+// register registers the methods of the examples of the JSON-RPC 2.0 specification on s.
+func register(s jsonrpc2.Server) {
+	jsonrpc2.RegisterFunc(s, "subtract", func(ctx context.Context, p subtractParams) (int, error) {
+		return p.Minuend - p.Subtrahend, nil
+	})
+	jsonrpc2.RegisterFunc(s, "update", func(ctx context.Context, p []int) (any, error) {
+		return nil, nil
+	})
+}
+
+// newRequest returns a request of method with params, or a notification if id is nil.
+func newRequest(t *testing.T, method string, params, id any) *jsonrpc2.Request {
+	t.Helper()
+	opts := []jsonrpc2.NewRequestOption{jsonrpc2.WithParams(params)}
+	if id != nil {
+		opts = append(opts, jsonrpc2.WithID(id))
+	}
+	req, err := jsonrpc2.NewRequest(method, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != nil {
+		return req
+	}
+	// Only requests decoded without "id" member are notifications.
+	data, err := json.Marshal(map[string]any{"jsonrpc": req.JSONRPC, "method": req.Method, "params": req.Params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err = jsonrpc2.UnmarshalRequest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    jsonrpc2.Server
+		urlPrefix string
+	}{
+		{name: "HTTP", server: jsonrpc2.NewHTTPServer("", "/rpc"), urlPrefix: "http://127.0.0.1:"},
+		{name: "WebSocket", server: jsonrpc2.NewWebSocketServer("", "/ws"), urlPrefix: "ws://127.0.0.1:"},
+		{name: "stream", server: jsonrpc2.NewTCPServer(""), urlPrefix: "tcp://127.0.0.1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			register(tt.server)
+			ts := jsonrpc2test.NewServer(tt.server)
+			defer ts.Close()
+			if !strings.HasPrefix(ts.URL, tt.urlPrefix) {
+				t.Errorf("got URL %q, want prefix %q", ts.URL, tt.urlPrefix)
+			}
+			if !strings.Contains(ts.URL, ts.Addr()) {
+				t.Errorf("got URL %q, want it to contain the address %q", ts.URL, ts.Addr())
+			}
+
+			ctx := testContext(t)
+			calls := []*jsonrpc2.Request{
+				newRequest(t, "subtract", []int{42, 23}, 1),
+				newRequest(t, "subtract", subtractParams{Minuend: 42, Subtrahend: 23}, 2),
+				newRequest(t, "foobar", nil, "3"),
+			}
+			for _, req := range calls {
+				if _, err := ts.Client.Call(ctx, req); err != nil {
+					t.Fatalf("call failed: %v", err)
+				}
+			}
+			if err := ts.Client.Notify(ctx, newRequest(t, "update", []int{1, 2, 3}, nil)); err != nil {
+				t.Fatalf("notify failed: %v", err)
+			}
+			// A batch of a call and a notification is answered with the response to the call only.
+			batch := []*jsonrpc2.Request{
+				newRequest(t, "subtract", []int{10, 4}, 4),
+				newRequest(t, "update", []int{4}, nil),
+			}
+			if _, err := ts.Client.CallBatch(ctx, batch); err != nil {
+				t.Fatalf("batch failed: %v", err)
+			}
+
+			// Every transport records the same transcript.
+			jsonrpc2test.AssertGolden(t, "testdata/server.golden", ts.Recorder.Transcript())
+		})
+	}
+}
+
+func TestNewServerUnsupported(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewServer of an unsupported server did not panic")
+		}
+	}()
+	jsonrpc2test.NewServer(jsonrpc2.NewStdioServer())
+}
+
+func TestServerClose(t *testing.T) {
+	srv := jsonrpc2.NewTCPServer("")
+	register(srv)
+	ts := jsonrpc2test.NewServer(srv)
+	ts.Close()
+
+	if _, err := ts.Client.Call(testContext(t), newRequest(t, "subtract", []int{1, 1}, 1)); err == nil {
+		t.Error("call after Close succeeded")
+	}
+}
@@ -0,0 +1,268 @@
+package jsonrpc2
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// LimitExceededData is the Data of a [LimitExceeded] error, and of an [InvalidRequest] error rejecting a message over a size limit.
+type LimitExceededData struct {
+	// The limit that was exceeded: "concurrency", "method", "connection", "peer" or "connections" for [LimitExceeded] errors,
+	// and "size", "batch", "depth" or "string" for [InvalidRequest] errors, see [WithMaxMessageSize].
+	Limit      string  `json:"limit"`
+	RetryAfter float64 `json:"retryAfter,omitempty"` // The number of seconds after which the request may succeed, if known.
+}
+
+// RateLimit describes a token bucket: requests are allowed at Rate per second on average, with bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64 // The number of requests allowed per second.
+	Burst int     // The maximum number of requests allowed at once. It is at least 1.
+}
+
+// WithMaxConcurrentHandlers limits the number of handlers a server runs at the same time, across all connections.
+// Requests arriving while n handlers are running are rejected with a [LimitExceeded] error.
+func WithMaxConcurrentHandlers(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxHandlers = n
+	}
+}
+
+// WithMethodRateLimit limits the rate at which a server accepts calls of method, across all clients.
+// Calls over the limit are rejected with a [LimitExceeded] error telling when to retry.
+// Calls rejected by authentication or authorization do not count against the limit, nor against [WithMaxConcurrentHandlers].
+func WithMethodRateLimit(method string, limit RateLimit) ServerOption {
+	return func(o *serverOptions) {
+		if o.methodLimits == nil {
+			o.methodLimits = make(map[string]RateLimit)
+		}
+		o.methodLimits[method] = limit
+	}
+}
+
+// WithConnectionRateLimit limits the rate at which a server accepts requests from each persistent connection.
+// HTTP requests are not bound to a connection; use [WithPeerRateLimit] to limit HTTP clients.
+func WithConnectionRateLimit(limit RateLimit) ServerOption {
+	return func(o *serverOptions) {
+		o.connLimit = &limit
+	}
+}
+
+// WithPeerRateLimit limits the rate at which a server accepts requests from each remote IP address, across all of its connections.
+func WithPeerRateLimit(limit RateLimit) ServerOption {
+	return func(o *serverOptions) {
+		o.peerLimit = &limit
+	}
+}
+
+// WithMaxConnections limits the number of connections a [StreamServer] or [WebSocketServer] serves at the same time.
+// Connections over the limit are answered with a [LimitExceeded] error, or 503 Service Unavailable for WebSocket, and closed.
+func WithMaxConnections(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxConns = n
+	}
+}
+
+// WithConnectionHandlers makes a [StreamServer] or [WebSocketServer] handle up to n messages of each connection at the same time.
+// Once n messages of a connection are being handled, no further message is read from it until one of them is answered,
+// and replies are sent as they complete, possibly out of order.
+// By default, the messages of a connection are handled one at a time, in the order they are received.
+func WithConnectionHandlers(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.connHandlers = n
+	}
+}
+
+// limiter enforces the limits configured by the options of a server.
+type limiter struct {
+	handlers chan struct{} // Semaphore of running handlers, nil if unlimited.
+	conns    chan struct{} // Semaphore of open connections, nil if unlimited.
+	methods  map[string]*tokenBucket
+	conn     *RateLimit
+
+	peerLimit *RateLimit
+	mu        sync.Mutex
+	peers     map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newLimiter creates a new [limiter] from the settings of o.
+func newLimiter(o *serverOptions) *limiter {
+	l := &limiter{
+		conn:      o.connLimit,
+		peerLimit: o.peerLimit,
+		peers:     make(map[string]*tokenBucket),
+	}
+	if o.maxHandlers > 0 {
+		l.handlers = make(chan struct{}, o.maxHandlers)
+	}
+	if o.maxConns > 0 {
+		l.conns = make(chan struct{}, o.maxConns)
+	}
+	if len(o.methodLimits) > 0 {
+		l.methods = make(map[string]*tokenBucket, len(o.methodLimits))
+		for method, limit := range o.methodLimits {
+			l.methods[method] = newTokenBucket(limit)
+		}
+	}
+	return l
+}
+
+// acquireConn reserves a connection and reports whether the limit allows it.
+// If so, releaseConn must be called when the connection is closed.
+func (l *limiter) acquireConn() bool {
+	if l.conns == nil {
+		return true
+	}
+	select {
+	case l.conns <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseConn releases a connection reserved by acquireConn.
+func (l *limiter) releaseConn() {
+	if l.conns != nil {
+		<-l.conns
+	}
+}
+
+// admit checks req against the limits of its connection and remote address.
+// It returns the response rejecting req, or nil if req is allowed.
+func (l *limiter) admit(ctx context.Context, req *Request) *Response {
+	now := time.Now()
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok && s.bucket != nil {
+		if wait, ok := s.bucket.take(now); !ok {
+			return limitExceeded(req.ID, "connection", wait)
+		}
+	}
+	if l.peerLimit != nil {
+		if wait, ok := l.peerBucket(ctx, now).take(now); !ok {
+			return limitExceeded(req.ID, "peer", wait)
+		}
+	}
+	return nil
+}
+
+// acquire checks req, once admitted and authorized, against the limits of its method and the number of running handlers.
+// If req is allowed, it returns a function releasing its handler slot, which must be called once req has been handled.
+// Otherwise, it returns the response rejecting req.
+func (l *limiter) acquire(ctx context.Context, req *Request) (func(), *Response) {
+	now := time.Now()
+	if b, ok := l.methods[req.Method]; ok {
+		if wait, ok := b.take(now); !ok {
+			return nil, limitExceeded(req.ID, "method", wait)
+		}
+	}
+
+	if l.handlers == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.handlers <- struct{}{}:
+		return func() { <-l.handlers }, nil
+	default:
+		return nil, limitExceeded(req.ID, "concurrency", 0)
+	}
+}
+
+// peerBucket returns the token bucket of the remote IP address of the connection ctx belongs to.
+// Buckets that have refilled completely are forgotten from time to time.
+func (l *limiter) peerBucket(ctx context.Context, now time.Time) *tokenBucket {
+	var host string
+	if p, ok := PeerFromContext(ctx); ok {
+		host = p.RemoteAddr
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.peers {
+			if b.full(now) {
+				delete(l.peers, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.peers[host]
+	if !ok {
+		b = newTokenBucket(*l.peerLimit)
+		l.peers[host] = b
+	}
+	return b
+}
+
+// newConnBucket returns the token bucket of a new connection, or nil if connections are not rate limited.
+func (l *limiter) newConnBucket() *tokenBucket {
+	if l.conn == nil {
+		return nil
+	}
+	return newTokenBucket(*l.conn)
+}
+
+// limitExceeded creates the response rejecting a request over limit.
+func limitExceeded(id any, limit string, retryAfter time.Duration) *Response {
+	return newErrorResponse(id, LimitExceeded, "Limit exceeded", WithData(&LimitExceededData{
+		Limit:      limit,
+		RetryAfter: math.Ceil(retryAfter.Seconds()*1000) / 1000,
+	}))
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a new full [tokenBucket].
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(max(limit.Burst, 1))
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call. The caller must hold b.mu.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// take takes a token and reports whether one was available.
+// If not, it returns how long to wait until one is.
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.rate <= 0 {
+		return 0, false
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)), false
+}
+
+// full reports whether the bucket has refilled completely.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= b.burst
+}
@@ -0,0 +1,93 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"errors"
+)
+
+// WithMaxMessageSize limits the size in bytes of the messages a server reads: HTTP request bodies,
+// lines of stream and stdio connections, and WebSocket messages.
+// HTTP requests over the limit are answered with 413 Content Too Large. Stream connections sending a longer line
+// are answered with an [InvalidRequest] error and closed, as the following messages cannot be found.
+// By default, lines of stream connections are limited to 64 KiB and WebSocket messages to 32 MiB.
+func WithMaxMessageSize(n int64) ServerOption {
+	return func(o *serverOptions) {
+		o.maxMessageSize = n
+	}
+}
+
+// WithMaxBatchSize limits the number of requests in a batch. Larger batches are rejected as a whole with an [InvalidRequest] error.
+func WithMaxBatchSize(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxBatchSize = n
+	}
+}
+
+// WithMaxDepth limits how deeply arrays and objects may be nested in a message, counting the request object,
+// and the batch array if any, e.g. 3 for a request whose params is an object of scalars.
+// Messages nested deeper are rejected with an [InvalidRequest] error before being decoded.
+func WithMaxDepth(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxStringLength limits the length in bytes of the strings in a message, as encoded in JSON.
+// Messages with longer strings are rejected with an [InvalidRequest] error before being decoded.
+func WithMaxStringLength(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.maxStringLength = n
+	}
+}
+
+// tooLarge creates the response rejecting a message over the given limit, see [LimitExceededData].
+func tooLarge(limit string) *Response {
+	return newErrorResponse(nil, InvalidRequest, "Invalid Request", WithData(&LimitExceededData{Limit: limit}))
+}
+
+// setScannerLimit makes scanner accept lines of up to the maximum message size of the server, if set.
+func (d *dispatcher) setScannerLimit(scanner *bufio.Scanner) {
+	if d.maxMessageSize > 0 {
+		scanner.Buffer(nil, int(d.maxMessageSize))
+	}
+}
+
+// scanError returns the response to send before closing a connection whose scanner failed with err, or nil.
+func scanError(err error) *Response {
+	if errors.Is(err, bufio.ErrTooLong) {
+		return tooLarge("size")
+	}
+	return nil
+}
+
+// checkStructure checks the nesting depth and the string lengths of the JSON value data against the limits of the server.
+// It returns the response rejecting data, or nil if data is within the limits. The validity of data is not checked.
+func (d *dispatcher) checkStructure(data []byte) *Response {
+	if d.maxDepth <= 0 && d.maxStringLength <= 0 {
+		return nil
+	}
+
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '[', '{':
+			depth++
+			if d.maxDepth > 0 && depth > d.maxDepth {
+				return tooLarge("depth")
+			}
+		case ']', '}':
+			depth--
+		case '"':
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if d.maxStringLength > 0 && i-start-1 > d.maxStringLength {
+				return tooLarge("string")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// Metrics receives measurements of the requests handled by a server or made by a client, see [WithMetrics] and
+// [WithClientMetrics]. Implementations must be safe for concurrent use; [ExpvarMetrics] is one.
+type Metrics interface {
+	// RequestStarted is called when a request, or a request of a batch, starts being handled or sent.
+	RequestStarted(method string)
+	// RequestFinished is called once a request started with RequestStarted is complete.
+	RequestFinished(stats RequestStats)
+	// Batch is called for each batch received or sent, with its number of requests.
+	Batch(size int)
+	// ConnOpened is called when a connection of transport, e.g. "tcp", "unix", "websocket" or "stdio", is established.
+	// The connections of an [HTTPServer] are reported as "http" when it is run, not when it is mounted as a handler.
+	ConnOpened(transport string)
+	// ConnClosed is called when a connection reported by ConnOpened is closed.
+	ConnClosed(transport string)
+}
+
+// RequestStats describes a complete request, see [Metrics].
+type RequestStats struct {
+	// The method of the request. Servers report an empty method for messages that are not valid requests
+	// and for methods they do not know, so that clients cannot make up arbitrary values.
+	Method   string
+	Code     ErrorCode     // The code of the error the request was answered with, or 0.
+	Err      error         // The error a call failed with before a response was received, if any. Only set by clients.
+	Duration time.Duration // How long the request took, from RequestStarted.
+}
+
+// WithMetrics makes a server report the requests it handles to m, including each request of a batch.
+// Stream, WebSocket and stdio servers also report their connections.
+func WithMetrics(m Metrics) ServerOption {
+	return func(o *serverOptions) {
+		o.metrics = m
+	}
+}
+
+// WithClientMetrics makes a client report the calls and notifications it sends to m.
+// A [TCPClient] also reports its connections.
+func WithClientMetrics(m Metrics) ClientOption {
+	return func(o *clientOptions) {
+		o.metrics = m
+	}
+}
+
+// startRequest reports the start of a request of method to m and returns a function reporting its end.
+// A nil m reports nothing.
+func startRequest(m Metrics, method string) func(resp *Response, err error) {
+	if m == nil {
+		return func(*Response, error) {}
+	}
+	start := time.Now()
+	m.RequestStarted(method)
+	return func(resp *Response, err error) {
+		stats := RequestStats{Method: method, Err: err, Duration: time.Since(start)}
+		if resp != nil && resp.Error != nil {
+			stats.Code = resp.Error.Code
+		}
+		m.RequestFinished(stats)
+	}
+}
+
+// startBatch reports the start of a batch of reqs to m and returns a function reporting the end of its requests
+// from the reply. A nil m reports nothing.
+func startBatch(m Metrics, reqs []*Request) func(reply []byte, err error) {
+	if m == nil {
+		return func([]byte, error) {}
+	}
+	m.Batch(len(reqs))
+	done := make([]func(*Response, error), len(reqs))
+	for i, req := range reqs {
+		done[i] = startRequest(m, req.Method)
+	}
+	return func(reply []byte, err error) {
+		codes := make(map[string]ErrorCode)
+		var resps []struct {
+			ID    json.RawMessage `json:"id"`
+			Error *Error          `json:"error"`
+		}
+		if err == nil && json.Unmarshal(reply, &resps) == nil {
+			for _, resp := range resps {
+				if resp.Error != nil {
+					codes[messageEnvelope{ID: resp.ID}.key()] = resp.Error.Code
+				}
+			}
+		}
+		for i, req := range reqs {
+			var resp *Response
+			if key, _ := idKey(req.ID); !req.IsNotification() && codes[key] != 0 {
+				resp = &Response{Error: &Error{Code: codes[key]}}
+			}
+			done[i](resp, err)
+		}
+	}
+}
+
+// openConn reports a connection of transport opened to m and returns a function reporting it closed.
+// A nil m reports nothing.
+func openConn(m Metrics, transport string) func() {
+	if m == nil {
+		return func() {}
+	}
+	m.ConnOpened(transport)
+	return func() { m.ConnClosed(transport) }
+}
+
+// measure returns a [Middleware] reporting the requests handled by d to m.
+func (d *dispatcher) measure(m Metrics) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) *Response {
+			method := req.Method
+			if !d.known(method) {
+				method = ""
+			}
+			done := startRequest(m, method)
+			resp := next(ctx, req)
+			done(resp, nil)
+			return resp
+		}
+	}
+}
+
+// reject reports resp, the error answering a message that is not a valid request, to the metrics of d, and returns it.
+func (d *dispatcher) reject(resp *Response) *Response {
+	startRequest(d.metrics, "")(resp, nil)
+	return resp
+}
+
+// ExpvarMetrics is a [Metrics] publishing its measurements with the [expvar] package, as a map with the members
+//
+//	requests          the number of requests, by method
+//	errors            the number of requests answered with an error, by code
+//	failures          the number of calls that failed without response, by method
+//	in_flight         the number of requests being handled or sent
+//	latency_seconds   the total time spent in requests, by method
+//	batches           the number of batches
+//	batch_requests    the total number of requests in batches
+//	connections       the number of open connections, by transport
+//
+// Average latencies and batch sizes are obtained by dividing the totals by the counts.
+type ExpvarMetrics struct {
+	requests    *expvar.Map
+	errors      *expvar.Map
+	failures    *expvar.Map
+	inFlight    *expvar.Int
+	latency     *expvar.Map
+	batches     *expvar.Int
+	batchSize   *expvar.Int
+	connections *expvar.Map
+}
+
+// NewExpvarMetrics creates a new [ExpvarMetrics] published under name, e.g. "jsonrpc2_server" and "jsonrpc2_client".
+// Like [expvar.Publish], it panics if name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		requests:    new(expvar.Map),
+		errors:      new(expvar.Map),
+		failures:    new(expvar.Map),
+		inFlight:    new(expvar.Int),
+		latency:     new(expvar.Map),
+		batches:     new(expvar.Int),
+		batchSize:   new(expvar.Int),
+		connections: new(expvar.Map),
+	}
+	vars := expvar.NewMap(name)
+	vars.Set("requests", m.requests)
+	vars.Set("errors", m.errors)
+	vars.Set("failures", m.failures)
+	vars.Set("in_flight", m.inFlight)
+	vars.Set("latency_seconds", m.latency)
+	vars.Set("batches", m.batches)
+	vars.Set("batch_requests", m.batchSize)
+	vars.Set("connections", m.connections)
+	return m
+}
+
+var _ Metrics = (*ExpvarMetrics)(nil)
+
+// RequestStarted implements [Metrics].
+func (m *ExpvarMetrics) RequestStarted(method string) {
+	m.inFlight.Add(1)
+}
+
+// RequestFinished implements [Metrics].
+func (m *ExpvarMetrics) RequestFinished(stats RequestStats) {
+	m.inFlight.Add(-1)
+	m.requests.Add(stats.Method, 1)
+	m.latency.AddFloat(stats.Method, stats.Duration.Seconds())
+	if stats.Code != 0 {
+		m.errors.Add(strconv.Itoa(int(stats.Code)), 1)
+	}
+	if stats.Err != nil {
+		m.failures.Add(stats.Method, 1)
+	}
+}
+
+// Batch implements [Metrics].
+func (m *ExpvarMetrics) Batch(size int) {
+	m.batches.Add(1)
+	m.batchSize.Add(int64(size))
+}
+
+// ConnOpened implements [Metrics].
+func (m *ExpvarMetrics) ConnOpened(transport string) {
+	m.connections.Add(transport, 1)
+}
+
+// ConnClosed implements [Metrics].
+func (m *ExpvarMetrics) ConnClosed(transport string) {
+	m.connections.Add(transport, -1)
+}
@@ -0,0 +1,22 @@
+package jsonrpc2
+
+// Middleware wraps the handling of requests by a server, e.g. to trace or measure them, see [WithMiddleware].
+type Middleware func(next Handler) Handler
+
+// WithMiddleware makes a server handle every request, including each request of a batch, through mws.
+// The first middleware is the outermost one. Middleware runs before limits and authorization are checked,
+// so it also sees the requests they reject and the errors of unknown methods and invalid params.
+// The responses to notifications are passed back through the middleware before being discarded.
+func WithMiddleware(mws ...Middleware) ServerOption {
+	return func(o *serverOptions) {
+		o.middleware = append(o.middleware, mws...)
+	}
+}
+
+// chainMiddleware returns handler wrapped by mws, the first one being the outermost.
+func chainMiddleware(handler Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	return handler
+}
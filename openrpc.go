@@ -0,0 +1,264 @@
+package jsonrpc2
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// discoverMethod is the method serving the OpenRPC document of a server.
+	discoverMethod = "rpc.discover"
+	// openRPCVersion is the version of the OpenRPC specification the served documents follow.
+	openRPCVersion = "1.3.2"
+)
+
+// MethodInfo describes a registered method.
+type MethodInfo struct {
+	Summary     string  // A short summary of what the method does.
+	Description string  // A verbose explanation of the method behavior.
+	Params      *Schema // The schema of the params, enforced before the handler is called. Object schemas list each property as a param.
+	Result      *Schema // The schema of the result.
+	Errors      []Error // The application errors the method may return.
+	Deprecated  bool    // Whether the method is deprecated and should not be used by new clients.
+
+	derivedParams *Schema // The schema of the params derived from their Go type, published if Params is nil.
+	derivedResult *Schema // The schema of the result derived from its Go type, published if Result is nil.
+}
+
+// RegisterOption defines a function type for describing a method when registering it, see [Describer].
+type RegisterOption func(*MethodInfo)
+
+// Describer is a [Server] whose methods can be described when registering them. The servers of this package implement it.
+type Describer interface {
+	Server
+	// RegisterMethod registers a handler for a specific method, described by opts in the document served by rpc.discover.
+	RegisterMethod(method string, handler Handler, opts ...RegisterOption)
+}
+
+// registerMethod registers handler for method on s, described by opts if s is a [Describer].
+func registerMethod(s Server, method string, handler Handler, opts []RegisterOption) {
+	if d, ok := s.(Describer); ok {
+		d.RegisterMethod(method, handler, opts...)
+		return
+	}
+	s.Register(method, handler)
+}
+
+// WithSummary sets a short summary of what the method does.
+func WithSummary(summary string) RegisterOption {
+	return func(m *MethodInfo) {
+		m.Summary = summary
+	}
+}
+
+// WithDescription sets a verbose explanation of the method behavior.
+func WithDescription(description string) RegisterOption {
+	return func(m *MethodInfo) {
+		m.Description = description
+	}
+}
+
+// WithParamsSchema sets the schema of the params of the method. See [SchemaFor] to derive it from a Go type.
+// Requests whose params do not match it are rejected with an [InvalidParams] error listing the violations as []SchemaViolation.
+func WithParamsSchema(schema *Schema) RegisterOption {
+	return func(m *MethodInfo) {
+		m.Params = schema
+	}
+}
+
+// WithResultSchema sets the schema of the result of the method. See [SchemaFor] to derive it from a Go type.
+// Results are only checked against it with [WithResultValidation].
+func WithResultSchema(schema *Schema) RegisterOption {
+	return func(m *MethodInfo) {
+		m.Result = schema
+	}
+}
+
+// withDerivedSchemas sets the schemas derived from the Go types of the params and result of the method.
+// Unlike those set with [WithParamsSchema], derived params schemas are only published, not enforced.
+func withDerivedSchemas(params, result *Schema) RegisterOption {
+	return func(m *MethodInfo) {
+		m.derivedParams = params
+		m.derivedResult = result
+	}
+}
+
+// paramsSchema returns the schema of the params published for the method.
+func (m *MethodInfo) paramsSchema() *Schema {
+	if m.Params != nil {
+		return m.Params
+	}
+	return m.derivedParams
+}
+
+// resultSchema returns the schema of the result published for the method.
+func (m *MethodInfo) resultSchema() *Schema {
+	if m.Result != nil {
+		return m.Result
+	}
+	return m.derivedResult
+}
+
+// WithMethodErrors adds errors the method may return.
+func WithMethodErrors(errs ...Error) RegisterOption {
+	return func(m *MethodInfo) {
+		m.Errors = append(m.Errors, errs...)
+	}
+}
+
+// WithDeprecated marks the method as deprecated.
+func WithDeprecated() RegisterOption {
+	return func(m *MethodInfo) {
+		m.Deprecated = true
+	}
+}
+
+// OpenRPCDocument is an OpenRPC document describing the methods of a server, as served by rpc.discover.
+// For more details, see: https://spec.open-rpc.org
+type OpenRPCDocument struct {
+	OpenRPC string          `json:"openrpc"` // The version of the OpenRPC specification.
+	Info    OpenRPCInfo     `json:"info"`    // Information about the service.
+	Methods []OpenRPCMethod `json:"methods"` // The methods of the service, sorted by name.
+}
+
+// OpenRPCInfo holds information about the service described by an [OpenRPCDocument].
+type OpenRPCInfo struct {
+	Title       string `json:"title"`                 // The title of the service.
+	Version     string `json:"version"`               // The version of the service.
+	Description string `json:"description,omitempty"` // A description of the service.
+}
+
+// OpenRPCMethod describes a method in an [OpenRPCDocument].
+type OpenRPCMethod struct {
+	Name           string              `json:"name"`
+	Summary        string              `json:"summary,omitempty"`
+	Description    string              `json:"description,omitempty"`
+	Params         []ContentDescriptor `json:"params"`
+	Result         *ContentDescriptor  `json:"result,omitempty"`
+	Errors         []Error             `json:"errors,omitempty"`
+	Deprecated     bool                `json:"deprecated,omitempty"`
+	ParamStructure string              `json:"paramStructure,omitempty"` // How params are passed: "by-name", "by-position" or "either".
+}
+
+// ContentDescriptor describes a param or result in an [OpenRPCDocument].
+type ContentDescriptor struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// WithOpenRPCInfo sets the information about the service included in the document served by rpc.discover.
+func WithOpenRPCInfo(info OpenRPCInfo) ServerOption {
+	return func(o *serverOptions) {
+		o.openRPCInfo = info
+	}
+}
+
+// WithoutDiscovery disables the rpc.discover method, which servers otherwise answer with an [OpenRPCDocument]
+// describing their methods, unless a handler is registered for it.
+func WithoutDiscovery() ServerOption {
+	return func(o *serverOptions) {
+		o.noDiscovery = true
+	}
+}
+
+// discover returns the OpenRPC document describing the registered methods.
+func (d *dispatcher) discover() *OpenRPCDocument {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	doc := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    d.openRPCInfo,
+		Methods: []OpenRPCMethod{},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "JSON-RPC 2.0 service"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+	for name, info := range d.methods {
+		if strings.HasPrefix(name, "rpc.") {
+			continue
+		}
+		doc.Methods = append(doc.Methods, info.openRPC(name))
+	}
+	slices.SortFunc(doc.Methods, func(a, b OpenRPCMethod) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return doc
+}
+
+// openRPC describes the method name in an [OpenRPCDocument].
+func (m *MethodInfo) openRPC(name string) OpenRPCMethod {
+	method := OpenRPCMethod{
+		Name:        name,
+		Summary:     m.Summary,
+		Description: m.Description,
+		Params:      []ContentDescriptor{},
+		Errors:      m.Errors,
+		Deprecated:  m.Deprecated,
+	}
+
+	params, result := m.paramsSchema(), m.resultSchema()
+	switch {
+	case params == nil:
+	case params.Type == "object" && len(params.Properties) > 0:
+		method.ParamStructure = "by-name"
+		props := params.propertyNames()
+		if positional := params.positional(); positional != nil {
+			method.ParamStructure = "either"
+			props = append(slices.Clone(positional), slices.DeleteFunc(props, func(p string) bool {
+				return slices.Contains(positional, p)
+			})...)
+		}
+		for _, prop := range props {
+			schema := params.Properties[prop]
+			method.Params = append(method.Params, ContentDescriptor{
+				Name:        prop,
+				Description: schema.Description,
+				Required:    slices.Contains(params.Required, prop),
+				Schema:      schema,
+			})
+		}
+	case params.Type == "array" && len(params.PrefixItems) > 0:
+		method.ParamStructure = "by-position"
+		for i, schema := range params.PrefixItems {
+			name := schema.Title
+			if name == "" {
+				name = fmt.Sprintf("param%d", i+1)
+			}
+			method.Params = append(method.Params, ContentDescriptor{
+				Name:        name,
+				Description: schema.Description,
+				Required:    params.MinItems == nil || i < *params.MinItems,
+				Schema:      schema,
+			})
+		}
+	default:
+		method.Params = append(method.Params, ContentDescriptor{
+			Name:        "params",
+			Description: params.Description,
+			Required:    true,
+			Schema:      params,
+		})
+	}
+
+	if result != nil {
+		method.Result = &ContentDescriptor{
+			Name:        "result",
+			Description: result.Description,
+			Schema:      result,
+		}
+	}
+	return method
+}
+
+// handleDiscover answers rpc.discover.
+func (d *dispatcher) handleDiscover(ctx context.Context, req *Request) *Response {
+	return NewResponse(req.ID, WithResult(d.discover()))
+}
@@ -0,0 +1,121 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// UnmarshalParams decodes params, given by name as an object or by position as an array, into the value pointed to by v.
+// When params is an array and v points to a struct, the elements are mapped onto the fields in the order set by
+// `jsonrpc:"N"` tags, starting from 0, or in declaration order if no field has such a tag.
+// Trailing elements may be omitted, leaving their fields unchanged. Otherwise, params is decoded with [json.Unmarshal].
+//
+//	type MoveParams struct {
+//		X     int      `json:"x" jsonrpc:"0"`
+//		Y     int      `json:"y" jsonrpc:"1"`
+//		Speed *float64 `json:"speed,omitempty" jsonrpc:"2"`
+//	}
+func UnmarshalParams(params json.RawMessage, v any) error {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || params[0] != '[' {
+		return json.Unmarshal(params, v)
+	}
+	names := positionalNames(reflect.TypeOf(v))
+	if names == nil {
+		return json.Unmarshal(params, v)
+	}
+
+	obj, err := positionalToNamed(params, names)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(obj, v)
+}
+
+// WithPositionalParams sets the Params field of a [Request] to params encoded by position, as an array.
+// Structs are encoded as the array of their fields in the order described by [UnmarshalParams], omitting trailing fields
+// left out by omitempty; other values are encoded as by [WithParams].
+func WithPositionalParams(params any) NewRequestOption {
+	return func(r *Request) error {
+		if params == nil {
+			return nil
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal params: %w", err)
+		}
+		if names := positionalNames(reflect.TypeOf(params)); names != nil {
+			if data, err = namedToPositional(data, names); err != nil {
+				return fmt.Errorf("failed to marshal params: %w", err)
+			}
+		}
+		r.Params = data
+		return nil
+	}
+}
+
+// positionalNamesCache caches the results of positionalNames by type.
+var positionalNamesCache sync.Map
+
+// positionalNames returns the JSON names of the fields of the struct t, or t points to, in positional order.
+// It returns nil for other types, and for structs not encoded as objects.
+func positionalNames(t reflect.Type) []string {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	if names, ok := positionalNamesCache.Load(t); ok {
+		return names.([]string)
+	}
+	// Structs encoded otherwise than as objects, e.g. by a MarshalJSON method, have no positional order.
+	var names []string
+	if s := schemaOf(t, make(map[reflect.Type]bool)); s.Type == "object" {
+		names = s.positional()
+		if names == nil {
+			names = []string{}
+		}
+	}
+	positionalNamesCache.Store(t, names)
+	return names
+}
+
+// positionalToNamed converts the JSON array params to an object whose properties are named by position after names.
+func positionalToNamed(params json.RawMessage, names []string) (json.RawMessage, error) {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(params, &elems); err != nil {
+		return nil, err
+	}
+	if len(elems) > len(names) {
+		return nil, fmt.Errorf("too many params: got %d, want at most %d", len(elems), len(names))
+	}
+	obj := make(map[string]json.RawMessage, len(elems))
+	for i, elem := range elems {
+		obj[names[i]] = elem
+	}
+	return json.Marshal(obj)
+}
+
+// namedToPositional converts the JSON object data to the array of its properties in the order of names.
+// Properties missing from data are encoded as null, or omitted if no later property is present.
+func namedToPositional(data []byte, names []string) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	elems := make([]json.RawMessage, len(names))
+	n := 0
+	for i, name := range names {
+		if elem, ok := obj[name]; ok {
+			elems[i] = elem
+			n = i + 1
+		} else {
+			elems[i] = json.RawMessage("null")
+		}
+	}
+	return json.Marshal(elems[:n])
+}
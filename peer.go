@@ -0,0 +1,62 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// Peer describes the remote end of the connection a request was received on.
+type Peer struct {
+	Network    string    // The network of the connection, e.g. "tcp" or "unix".
+	RemoteAddr string    // The address of the remote end, if known.
+	Cred       *PeerCred // The credentials of the peer process. It is only set for Unix domain sockets on Linux.
+
+	// TLS is the state of the TLS connection, if any. With mutual TLS, its VerifiedChains hold the verified client certificate chains.
+	TLS *tls.ConnectionState
+}
+
+// PeerCred holds the credentials of the process on the other end of a Unix domain socket,
+// as reported by the SO_PEERCRED socket option when the connection was established.
+type PeerCred struct {
+	PID int32  // The process ID.
+	UID uint32 // The user ID.
+	GID uint32 // The group ID.
+}
+
+// peerKey is the context key for the [Peer] of the current connection.
+type peerKey struct{}
+
+// PeerFromContext returns the [Peer] a request was received from.
+// It is available to handlers of all network transports.
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
+
+// contextWithPeer returns a copy of ctx carrying p.
+func contextWithPeer(ctx context.Context, p *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// newPeer describes the remote end of conn.
+func newPeer(conn net.Conn) *Peer {
+	p := &Peer{}
+	if addr := conn.LocalAddr(); addr != nil {
+		p.Network = addr.Network()
+	}
+	if addr := conn.RemoteAddr(); addr != nil {
+		p.RemoteAddr = addr.String()
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		p.TLS = &state
+		conn = tlsConn.NetConn()
+	}
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		if cred, err := peerCred(unixConn); err == nil {
+			p.Cred = cred
+		}
+	}
+	return p
+}
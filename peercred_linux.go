@@ -0,0 +1,37 @@
+//go:build linux
+
+package jsonrpc2
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// peerCred returns the credentials of the process on the other end of conn.
+func peerCred(conn *net.UnixConn) (*PeerCred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+
+	var (
+		ucred   *syscall.Ucred
+		credErr error
+	)
+	err = raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+	if credErr != nil {
+		return nil, fmt.Errorf("failed to get peer credentials: %w", credErr)
+	}
+
+	return &PeerCred{
+		PID: ucred.Pid,
+		UID: ucred.Uid,
+		GID: ucred.Gid,
+	}, nil
+}
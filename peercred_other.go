@@ -0,0 +1,14 @@
+//go:build !linux
+
+package jsonrpc2
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCred returns the credentials of the process on the other end of conn.
+// Peer credentials are only supported on Linux.
+func peerCred(*net.UnixConn) (*PeerCred, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,167 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// Faults describes faults injected into the newline-delimited messages written to a connection, see [FaultyConn].
+type Faults struct {
+	Delay        time.Duration // How long each message is held before being written.
+	DropRate     float64       // The probability, from 0 to 1, that a message is not written at all.
+	TruncateRate float64       // The probability, from 0 to 1, that only the first half of a message is written, followed by a newline.
+	Seed         uint64        // The seed of the random decisions, so that runs can be reproduced.
+}
+
+// FaultyConn returns conn with faults injected into the messages written to it. Messages are delimited by newlines,
+// as sent by [TCPClient], [StreamServer] and [StdioServer]; a message is held until its newline is written.
+func FaultyConn(conn net.Conn, faults Faults) net.Conn {
+	return &faultyConn{
+		Conn:   conn,
+		faults: faults,
+		rand:   rand.New(rand.NewPCG(faults.Seed, faults.Seed)),
+	}
+}
+
+// faultyConn is a [net.Conn] injecting [Faults] into the messages written to it.
+type faultyConn struct {
+	net.Conn
+	faults Faults
+
+	mu   sync.Mutex
+	rand *rand.Rand
+	buf  []byte // The start of a message whose newline was not written yet.
+}
+
+func (c *faultyConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buf = append(c.buf, p...)
+	for {
+		i := bytes.IndexByte(c.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		msg := c.buf[:i+1]
+		c.buf = c.buf[i+1:]
+		if len(c.buf) == 0 {
+			c.buf = nil
+		}
+
+		if c.faults.Delay > 0 {
+			time.Sleep(c.faults.Delay)
+		}
+		switch {
+		case c.rand.Float64() < c.faults.DropRate:
+			continue
+		case c.rand.Float64() < c.faults.TruncateRate:
+			msg = append(msg[:len(msg)/2:len(msg)/2], '\n')
+		}
+		if _, err := c.Conn.Write(msg); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// PipeListener is an in-memory [net.Listener] whose connections are opened by [PipeListener.Dial], e.g.
+//
+//	ln := jsonrpc2.NewPipeListener()
+//	go server.Serve(ctx, ln)
+//	client := jsonrpc2.NewTCPClientWithDialer(ln.Dial)
+//
+// It links clients to servers within a process, such as in tests, without opening sockets.
+type PipeListener struct {
+	requestFaults  *Faults
+	responseFaults *Faults
+	conns          chan net.Conn
+	done           chan struct{}
+	closeOnce      sync.Once
+}
+
+// PipeOption defines a function type for setting optional fields of a [PipeListener].
+type PipeOption func(*PipeListener)
+
+// WithRequestFaults injects faults into the messages the dialing side writes, see [Faults].
+func WithRequestFaults(faults Faults) PipeOption {
+	return func(l *PipeListener) {
+		l.requestFaults = &faults
+	}
+}
+
+// WithResponseFaults injects faults into the messages the accepting side writes, see [Faults].
+func WithResponseFaults(faults Faults) PipeOption {
+	return func(l *PipeListener) {
+		l.responseFaults = &faults
+	}
+}
+
+// NewPipeListener creates a new [PipeListener].
+func NewPipeListener(opts ...PipeOption) *PipeListener {
+	l := &PipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+var _ net.Listener = (*PipeListener)(nil)
+
+// Dial opens a connection to the listener with [net.Pipe], waiting for it to be accepted. It is a [Dialer].
+func (l *PipeListener) Dial(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	if l.requestFaults != nil {
+		client = FaultyConn(client, *l.requestFaults)
+	}
+	if l.responseFaults != nil {
+		server = FaultyConn(server, *l.responseFaults)
+	}
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		client.Close()
+		return nil, &net.OpError{Op: "dial", Net: pipeNetwork, Err: net.ErrClosed}
+	case <-ctx.Done():
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// Accept waits for the next connection opened by [PipeListener.Dial].
+func (l *PipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, &net.OpError{Op: "accept", Net: pipeNetwork, Err: net.ErrClosed}
+	}
+}
+
+// Close stops the listener. Connections already accepted are not closed.
+func (l *PipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns the address of the listener, which has the network "pipe".
+func (l *PipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// pipeNetwork is the network of the addresses of [PipeListener] and of its connections.
+const pipeNetwork = "pipe"
+
+// pipeAddr is the address of a [PipeListener].
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return pipeNetwork }
+func (pipeAddr) String() string  { return pipeNetwork }
@@ -0,0 +1,320 @@
+package jsonrpc2_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+type addParams struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+// servePipe serves a stream server with the test methods on a new pipe listener until the test ends.
+func servePipe(t *testing.T, pipeOpts []jsonrpc2.PipeOption, opts ...jsonrpc2.ServerOption) *jsonrpc2.PipeListener {
+	t.Helper()
+	s := jsonrpc2.NewTCPServer("", opts...)
+	jsonrpc2.RegisterFunc(s, "add", func(ctx context.Context, p addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+	jsonrpc2.RegisterFunc(s, "fail", func(ctx context.Context, p struct{}) (any, error) {
+		return nil, errors.New("boom")
+	})
+	s.Register("ping", func(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Response {
+		return jsonrpc2.NewResponse(req.ID, jsonrpc2.WithResult("pong"))
+	})
+
+	ln := jsonrpc2.NewPipeListener(pipeOpts...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln
+}
+
+// exchange sends msg over a new connection of ln and returns the reply, or nil if none is received within
+// a short while, as for notifications.
+func exchange(t *testing.T, ln *jsonrpc2.PipeListener, msg string, wait time.Duration) json.RawMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := ln.Dial(ctx)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(msg + "\n")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(wait))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	return line
+}
+
+// normalize decodes a message for comparison, dropping the data of errors, which carries details such as retry delays.
+func normalize(t *testing.T, msg []byte) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(msg, &v); err != nil {
+		t.Fatalf("invalid message %s: %v", msg, err)
+	}
+	drop := func(v any) {
+		if obj, ok := v.(map[string]any); ok {
+			if e, ok := obj["error"].(map[string]any); ok {
+				delete(e, "data")
+			}
+		}
+	}
+	if batch, ok := v.([]any); ok {
+		for _, item := range batch {
+			drop(item)
+		}
+	} else {
+		drop(v)
+	}
+	return v
+}
+
+func TestPipeListener(t *testing.T) {
+	denyAll := jsonrpc2.AuthenticatorFunc(func(ctx context.Context, cred *jsonrpc2.Credentials) (*jsonrpc2.Principal, error) {
+		return nil, errors.New("denied")
+	})
+	tests := []struct {
+		name string
+		opts []jsonrpc2.ServerOption
+		send string
+		want string // Empty if no reply is expected.
+	}{
+		{
+			name: "request",
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`,
+			want: `{"jsonrpc":"2.0","result":3,"id":1}`,
+		},
+		{
+			name: "request with string id and positional params",
+			send: `{"jsonrpc":"2.0","method":"add","params":[4,5],"id":"a"}`,
+			want: `{"jsonrpc":"2.0","result":9,"id":"a"}`,
+		},
+		{
+			name: "request with null id",
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":1},"id":null}`,
+			want: `{"jsonrpc":"2.0","result":2,"id":null}`,
+		},
+		{
+			name: "notification",
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2}}`,
+		},
+		{
+			name: "batch",
+			send: `[{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1},{"jsonrpc":"2.0","method":"add","params":{"a":3,"b":4},"id":2}]`,
+			want: `[{"jsonrpc":"2.0","result":3,"id":1},{"jsonrpc":"2.0","result":7,"id":2}]`,
+		},
+		{
+			name: "batch with notification",
+			send: `[{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2}},{"jsonrpc":"2.0","method":"add","params":{"a":3,"b":4},"id":2}]`,
+			want: `[{"jsonrpc":"2.0","result":7,"id":2}]`,
+		},
+		{
+			name: "batch of notifications",
+			send: `[{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2}},{"jsonrpc":"2.0","method":"ping"}]`,
+		},
+		{
+			name: "batch with invalid request",
+			send: `[{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1},1]`,
+			want: `[{"jsonrpc":"2.0","result":3,"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]`,
+		},
+		{
+			name: "parse error",
+			send: `{"jsonrpc":"2.0","method":"add",`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`,
+		},
+		{
+			name: "invalid request",
+			send: `{"jsonrpc":"1.0","method":"add","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "empty batch",
+			send: `[]`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`,
+		},
+		{
+			name: "method not found",
+			send: `{"jsonrpc":"2.0","method":"nope","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`,
+		},
+		{
+			name: "invalid params",
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":"x"},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":1}`,
+		},
+		{
+			name: "internal error",
+			send: `{"jsonrpc":"2.0","method":"fail","id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`,
+		},
+		{
+			name: "unauthorized",
+			opts: []jsonrpc2.ServerOption{jsonrpc2.WithAuthenticator(denyAll)},
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32001,"message":"Unauthorized"},"id":1}`,
+		},
+		{
+			name: "forbidden",
+			opts: []jsonrpc2.ServerOption{jsonrpc2.WithAuthorizer(func(ctx context.Context, p *jsonrpc2.Principal, req *jsonrpc2.Request) bool {
+				return req.Method != "add"
+			})},
+			send: `{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1}`,
+			want: `{"jsonrpc":"2.0","error":{"code":-32003,"message":"Forbidden"},"id":1}`,
+		},
+		{
+			name: "limit exceeded",
+			opts: []jsonrpc2.ServerOption{jsonrpc2.WithMethodRateLimit("add", jsonrpc2.RateLimit{Rate: 0.001, Burst: 1})},
+			send: `[{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":1},{"jsonrpc":"2.0","method":"add","params":{"a":1,"b":2},"id":2}]`,
+			want: `[{"jsonrpc":"2.0","result":3,"id":1},{"jsonrpc":"2.0","error":{"code":-32005,"message":"Limit exceeded"},"id":2}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln := servePipe(t, nil, tt.opts...)
+			if tt.want == "" {
+				if got := exchange(t, ln, tt.send, 100*time.Millisecond); got != nil {
+					t.Fatalf("got reply %s, want none", got)
+				}
+				return
+			}
+			got := exchange(t, ln, tt.send, 5*time.Second)
+			if got == nil {
+				t.Fatalf("got no reply, want %s", tt.want)
+			}
+			if !reflect.DeepEqual(normalize(t, got), normalize(t, []byte(tt.want))) {
+				t.Errorf("got reply %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeListenerFaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		pipeOpts  []jsonrpc2.PipeOption
+		timeout   time.Duration
+		minTime   time.Duration
+		wantCode  jsonrpc2.ErrorCode
+		wantError error
+	}{
+		{
+			name:    "no faults",
+			timeout: 5 * time.Second,
+		},
+		{
+			name:     "delayed requests",
+			pipeOpts: []jsonrpc2.PipeOption{jsonrpc2.WithRequestFaults(jsonrpc2.Faults{Delay: 50 * time.Millisecond})},
+			timeout:  5 * time.Second,
+			minTime:  50 * time.Millisecond,
+		},
+		{
+			name:     "delayed responses",
+			pipeOpts: []jsonrpc2.PipeOption{jsonrpc2.WithResponseFaults(jsonrpc2.Faults{Delay: 50 * time.Millisecond})},
+			timeout:  5 * time.Second,
+			minTime:  50 * time.Millisecond,
+		},
+		{
+			name:      "dropped requests",
+			pipeOpts:  []jsonrpc2.PipeOption{jsonrpc2.WithRequestFaults(jsonrpc2.Faults{DropRate: 1})},
+			timeout:   100 * time.Millisecond,
+			wantError: context.DeadlineExceeded,
+		},
+		{
+			name:      "dropped responses",
+			pipeOpts:  []jsonrpc2.PipeOption{jsonrpc2.WithResponseFaults(jsonrpc2.Faults{DropRate: 1})},
+			timeout:   100 * time.Millisecond,
+			wantError: context.DeadlineExceeded,
+		},
+		{
+			name:     "truncated requests",
+			pipeOpts: []jsonrpc2.PipeOption{jsonrpc2.WithRequestFaults(jsonrpc2.Faults{TruncateRate: 1})},
+			timeout:  5 * time.Second,
+			wantCode: jsonrpc2.ParseError,
+		},
+		{
+			name:      "truncated responses",
+			pipeOpts:  []jsonrpc2.PipeOption{jsonrpc2.WithResponseFaults(jsonrpc2.Faults{TruncateRate: 1})},
+			timeout:   100 * time.Millisecond,
+			wantError: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln := servePipe(t, tt.pipeOpts)
+			client := jsonrpc2.NewTCPClientWithDialer(ln.Dial)
+			defer client.Close()
+
+			req, err := jsonrpc2.NewRequest("add", jsonrpc2.WithParams(addParams{A: 1, B: 2}), jsonrpc2.WithID(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			start := time.Now()
+			resp, err := client.Call(ctx, req)
+			if elapsed := time.Since(start); elapsed < tt.minTime {
+				t.Errorf("call took %v, want at least %v", elapsed, tt.minTime)
+			}
+
+			if tt.wantError != nil {
+				if !errors.Is(err, tt.wantError) {
+					t.Fatalf("got error %v, want %v", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Fatalf("got response %+v, want error code %d", resp, tt.wantCode)
+				}
+				return
+			}
+			var sum int
+			if err := resp.DecodeResult(&sum); err != nil || sum != 3 {
+				t.Errorf("got result %v (%v), want 3", sum, err)
+			}
+		})
+	}
+}
+
+func TestPipeListenerClose(t *testing.T) {
+	ln := jsonrpc2.NewPipeListener()
+	ln.Close()
+	if _, err := ln.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept after Close: got %v, want %v", err, net.ErrClosed)
+	}
+	if _, err := ln.Dial(context.Background()); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Dial after Close: got %v, want %v", err, net.ErrClosed)
+	}
+}
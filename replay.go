@@ -0,0 +1,156 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ReplayResult is the outcome of a message sent again by [Replay].
+type ReplayResult struct {
+	Event    TapEvent        // The event the message was recorded in.
+	Recorded json.RawMessage // The reply recorded for the message, if any.
+	Reply    json.RawMessage // The reply received, or nil for a notification or a failed call.
+	Err      error           // The error the call failed with, if any.
+}
+
+// Match reports whether the reply received is the one recorded, regardless of formatting and of the order of object members.
+func (r ReplayResult) Match() bool {
+	if r.Err != nil || r.Reply == nil || r.Recorded == nil {
+		return r.Err == nil && r.Reply == nil && r.Recorded == nil
+	}
+	var recorded, reply any
+	if decodeJSON(r.Recorded, &recorded, true) != nil || decodeJSON(r.Reply, &reply, true) != nil {
+		return false
+	}
+	return reflect.DeepEqual(recorded, reply)
+}
+
+// Replay sends the requests, notifications and batches that clients sent in a session recorded by a [Tap]
+// through c, and returns their outcomes along with the replies recorded for them, e.g.
+//
+//	events, err := jsonrpc2.ReadTap(f)
+//	...
+//	results, err := jsonrpc2.Replay(ctx, client, events)
+//
+// Sessions recorded by clients or servers are both accepted; if the session was recorded by both, the messages
+// recorded by clients are sent. Messages are sent one at a time, in the order they were recorded.
+// Create c with [WithRawResults] or [WithUseNumber] so that large numbers in the replies are compared exactly.
+// The error is only set if ctx is done, in which case the results of the messages sent so far are returned.
+func Replay(ctx context.Context, c Client, events []TapEvent) ([]ReplayResult, error) {
+	role := TapServer
+	for _, e := range events {
+		if e.Role == TapClient {
+			role = TapClient
+			break
+		}
+	}
+	sent, received := TapSend, TapReceive
+	if role == TapServer {
+		sent, received = TapReceive, TapSend
+	}
+
+	replies := make(map[string]json.RawMessage)
+	for _, e := range events {
+		if e.Role == role && e.Direction == received && e.Message != nil {
+			for _, key := range replayKeys(e, false) {
+				replies[key] = e.Message
+			}
+		}
+	}
+
+	var results []ReplayResult
+	for _, e := range events {
+		if e.Role != role || e.Direction != sent || e.Message == nil {
+			continue
+		}
+		keys := replayKeys(e, true)
+		if len(keys) == 0 && !isNotification(e.Message) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := ReplayResult{Event: e}
+		if len(keys) > 0 {
+			result.Recorded = replies[keys[0]]
+		}
+		result.Reply, result.Err = replay(ctx, c, e.Message)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// replay sends msg, a request, notification or batch, through c and returns the reply.
+// IDs are sent as recorded, including null ones, and batches of notifications are sent without expecting a reply.
+func replay(ctx context.Context, c Client, msg json.RawMessage) (json.RawMessage, error) {
+	if msg[0] == '[' {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(msg, &raws); err != nil {
+			return nil, fmt.Errorf("failed to decode batch: %w", err)
+		}
+		reqs := make([]*Request, len(raws))
+		for i, raw := range raws {
+			reqs[i] = &Request{}
+			if err := reqs[i].unmarshal(raw, true); err != nil {
+				return nil, fmt.Errorf("failed to decode batch: %w", err)
+			}
+		}
+		reply, err := c.CallBatch(ctx, reqs)
+		if err != nil || reply == nil {
+			return nil, err
+		}
+		return json.Marshal(reply)
+	}
+
+	var req Request
+	if err := req.unmarshal(msg, true); err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+	if req.IsNotification() {
+		return nil, c.Notify(ctx, &req)
+	}
+	resp, err := c.Call(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	if raw := resp.RawResult(); raw != nil {
+		rawResp := *resp
+		rawResp.Result = raw
+		resp = &rawResp
+	}
+	return json.Marshal(resp)
+}
+
+// replayKeys returns the keys matching requests with their replies: the connection the message was recorded on and
+// the ID of each of its requests, or of each of its replies, as requested. Null IDs are keys too.
+func replayKeys(e TapEvent, requests bool) []string {
+	envelopes, err := messageEnvelopes(e.Message)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, env := range envelopes {
+		if env.key() != "" && (env.Method != "") == requests {
+			keys = append(keys, strconv.FormatUint(e.Conn, 10)+"/"+env.key())
+		}
+	}
+	return keys
+}
+
+// isNotification reports whether msg is a notification, or a batch of them.
+func isNotification(msg json.RawMessage) bool {
+	envelopes, err := messageEnvelopes(msg)
+	if err != nil || len(envelopes) == 0 {
+		return false
+	}
+	for _, env := range envelopes {
+		if env.Method == "" || env.key() != "" {
+			return false
+		}
+	}
+	return true
+}
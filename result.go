@@ -0,0 +1,140 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// resultDecoding selects how clients decode the results of responses, see [WithRawResults] and [WithUseNumber].
+type resultDecoding int
+
+const (
+	decodeAny     resultDecoding = iota // Results are decoded as by [json.Unmarshal] into an any, numbers becoming float64.
+	decodeNumbers                       // Results are decoded into an any, numbers becoming [json.Number].
+	decodeRaw                           // Results are kept as [json.RawMessage].
+)
+
+// WithRawResults makes a client keep the Result field of responses as the [json.RawMessage] received, to be decoded
+// by the caller, e.g. with [Response.DecodeResult]. IDs and the results of batches are decoded as with [WithUseNumber].
+func WithRawResults() ClientOption {
+	return func(o *clientOptions) {
+		o.resultDecoding = decodeRaw
+	}
+}
+
+// WithUseNumber makes a client decode numbers in the Result and ID fields of responses and in batches as [json.Number]
+// rather than float64, so that integers beyond 2^53 are kept exactly.
+func WithUseNumber() ClientOption {
+	return func(o *clientOptions) {
+		o.resultDecoding = decodeNumbers
+	}
+}
+
+// WithServerUseNumber makes a server decode numeric request IDs as [json.Number] rather than float64, so that
+// integers beyond 2^53 are echoed exactly. Handlers then see such IDs as [json.Number].
+func WithServerUseNumber() ServerOption {
+	return func(o *serverOptions) {
+		o.useNumber = true
+	}
+}
+
+// WithRawResult sets the Result field of a [Response] to result, pre-encoded JSON that is sent without being decoded.
+func WithRawResult(result json.RawMessage) NewResponseOption {
+	return func(r *Response) {
+		r.Result = result
+		r.rawResult = result
+	}
+}
+
+// RawResult returns the encoding of the result as received, or nil if the response was not decoded from JSON
+// or has no result.
+func (r *Response) RawResult() json.RawMessage {
+	return r.rawResult
+}
+
+// DecodeResult decodes the result into the value pointed to by v, from the encoding received if any, so that
+// numbers are decoded exactly. It returns the error of the response, see [Response.Err], if the request failed.
+func (r *Response) DecodeResult(v any) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	data := r.rawResult
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(r.Result); err != nil {
+			return fmt.Errorf("failed to marshal result: %w", err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], decoding the result as by [json.Unmarshal] into an any
+// and keeping its encoding for [Response.DecodeResult].
+func (r *Response) UnmarshalJSON(data []byte) error {
+	return r.unmarshal(data, decodeAny)
+}
+
+// unmarshal decodes the response data, decoding the result according to mode.
+func (r *Response) unmarshal(data []byte, mode resultDecoding) error {
+	var v struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result"`
+		Error   *Error          `json:"error"`
+		ID      any             `json:"id"`
+	}
+	if err := decodeJSON(data, &v, mode != decodeAny); err != nil {
+		return err
+	}
+
+	*r = Response{JSONRPC: v.JSONRPC, Error: v.Error, ID: v.ID, header: r.header}
+	if v.Result == nil {
+		return nil
+	}
+	r.rawResult = v.Result
+	if mode == decodeRaw {
+		r.Result = v.Result
+		return nil
+	}
+	return decodeJSON(v.Result, &r.Result, mode == decodeNumbers)
+}
+
+// decodeResponse decodes a single response received by a client, decoding the result according to mode.
+func decodeResponse(data []byte, mode resultDecoding) (*Response, error) {
+	var resp Response
+	if err := resp.unmarshal(data, mode); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// decodeBatch decodes a single or batch response received by a client into an any, with numbers as [json.Number]
+// unless mode is decodeAny.
+func decodeBatch(data []byte, mode resultDecoding) (any, error) {
+	var resp any
+	if err := decodeJSON(data, &resp, mode != decodeAny); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// decodeJSON decodes data into the value pointed to by v, with numbers in an any decoded as [json.Number] if useNumber is set.
+func decodeJSON(data []byte, v any, useNumber bool) error {
+	if !useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes when and how a [RetryClient] retries failed calls.
+type RetryPolicy struct {
+	MaxAttempts        int                      // The maximum number of attempts, including the first one. Defaults to 3.
+	Backoff            Backoff                  // The delay schedule between attempts.
+	Idempotent         func(method string) bool // Reports whether a method is safe to retry. If nil, no method is retried.
+	RetryCodes         []ErrorCode              // JSON-RPC error codes that are retried for idempotent methods.
+	RetryNotifications bool                     // Whether notifications of idempotent methods are retried.
+	RetryBatches       bool                     // Whether batches whose methods are all idempotent are retried.
+	Retryable          func(err error) bool     // Reports whether a transport error is transient. If nil, [IsTransientError] is used.
+}
+
+// MethodSet returns a function that reports whether a method is one of methods.
+// It is meant to be used as [RetryPolicy.Idempotent].
+func MethodSet(methods ...string) func(method string) bool {
+	set := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		set[m] = struct{}{}
+	}
+	return func(method string) bool {
+		_, ok := set[method]
+		return ok
+	}
+}
+
+// IsTransientError reports whether err is a transport failure that may succeed when retried,
+// such as a refused or reset connection, a lost connection or an HTTP 502, 503 or 504 status.
+// Errors caused by the caller's context are never transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryClient is a [Client] that retries failed calls of an underlying client according to a [RetryPolicy].
+type RetryClient struct {
+	client Client
+	policy RetryPolicy
+}
+
+// NewRetryClient creates a new [RetryClient] that wraps client.
+func NewRetryClient(client Client, policy RetryPolicy) *RetryClient {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsTransientError
+	}
+	return &RetryClient{
+		client: client,
+		policy: policy,
+	}
+}
+
+var _ Client = (*RetryClient)(nil)
+
+var _ BatchCaller = (*RetryClient)(nil)
+
+// Call sends a JSON-RPC 2.0 request, retrying it while the policy allows.
+// A response carrying one of [RetryPolicy.RetryCodes] is retried like a transport error.
+func (c *RetryClient) Call(ctx context.Context, req *Request) (*Response, error) {
+	var (
+		resp *Response
+		err  error
+	)
+	retry := func(ctx context.Context) (bool, error) {
+		resp, err = c.client.Call(ctx, req)
+		if err != nil {
+			return c.policy.Retryable(err), err
+		}
+		if resp.Error != nil && slices.Contains(c.policy.RetryCodes, resp.Error.Code) {
+			return true, resp.Error
+		}
+		return false, nil
+	}
+
+	if !c.idempotent(req) {
+		return c.client.Call(ctx, req)
+	}
+	if attemptErr := c.do(ctx, retry); attemptErr != nil && err != nil {
+		return nil, attemptErr
+	}
+	return resp, nil
+}
+
+// Go sends a JSON-RPC request without waiting for the response, retrying as [RetryClient.Call] does, see [Call].
+func (c *RetryClient) Go(ctx context.Context, req *Request) *Call {
+	return Go(ctx, req, c.Call)
+}
+
+// CallBatch sends a batch of JSON-RPC requests.
+// The batch is retried on transport errors only if [RetryPolicy.RetryBatches] is set and all of its methods are idempotent.
+func (c *RetryClient) CallBatch(ctx context.Context, reqs []*Request) (any, error) {
+	return retryBatch(ctx, c, reqs, c.client.CallBatch)
+}
+
+// CallBatchResponses implements [BatchCaller], retrying as [RetryClient.CallBatch] does.
+func (c *RetryClient) CallBatchResponses(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	return retryBatch(ctx, c, reqs, func(ctx context.Context, reqs []*Request) ([]*Response, error) {
+		return CallBatchResponses(ctx, c.client, reqs)
+	})
+}
+
+// retryBatch sends reqs with send, retrying as [RetryClient.CallBatch] does.
+func retryBatch[T any](ctx context.Context, c *RetryClient, reqs []*Request, send func(context.Context, []*Request) (T, error)) (T, error) {
+	if !c.policy.RetryBatches || slices.ContainsFunc(reqs, c.notIdempotent) {
+		return send(ctx, reqs)
+	}
+
+	var resp T
+	err := c.do(ctx, func(ctx context.Context) (bool, error) {
+		var err error
+		resp, err = send(ctx, reqs)
+		return err != nil && c.policy.Retryable(err), err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return resp, nil
+}
+
+// Notify sends a JSON-RPC notification.
+// The notification is retried on transport errors only if [RetryPolicy.RetryNotifications] is set and its method is idempotent.
+func (c *RetryClient) Notify(ctx context.Context, req *Request) error {
+	if !c.policy.RetryNotifications || !c.idempotent(req) {
+		return c.client.Notify(ctx, req)
+	}
+
+	return c.do(ctx, func(ctx context.Context) (bool, error) {
+		err := c.client.Notify(ctx, req)
+		return err != nil && c.policy.Retryable(err), err
+	})
+}
+
+// do runs attempt until it succeeds, reports a permanent failure, the attempts are exhausted
+// or the next delay would outlast ctx. It returns the error of the last attempt.
+func (c *RetryClient) do(ctx context.Context, attempt func(ctx context.Context) (bool, error)) error {
+	for n := 1; ; n++ {
+		retryable, err := attempt(ctx)
+		if err == nil || !retryable {
+			return err
+		}
+		if n >= c.policy.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", n, err)
+		}
+
+		delay := c.policy.Backoff.Delay(n)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+func (c *RetryClient) idempotent(req *Request) bool {
+	return c.policy.Idempotent != nil && c.policy.Idempotent(req.Method)
+}
+
+func (c *RetryClient) notIdempotent(req *Request) bool {
+	return !c.idempotent(req)
+}
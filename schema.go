@@ -0,0 +1,236 @@
+package jsonrpc2
+
+import (
+	"encoding"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema describing params or results, as used in OpenRPC documents.
+// Only the keywords needed to describe Go types and common constraints are supported.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`                 // The JSON type: "object", "array", "string", "number", "integer", "boolean" or "null". Empty allows any value.
+	Nullable             bool               `json:"-"`                              // Whether null is allowed besides Type. It is encoded in the type keyword.
+	Title                string             `json:"title,omitempty"`                // A short title.
+	Description          string             `json:"description,omitempty"`          // A description of the value.
+	Format               string             `json:"format,omitempty"`               // The format of strings, e.g. "date-time".
+	Enum                 []any              `json:"enum,omitempty"`                 // The allowed values.
+	Properties           map[string]*Schema `json:"properties,omitempty"`           // The schemas of the properties of objects.
+	Required             []string           `json:"required,omitempty"`             // The properties objects must have.
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"` // The schema of the properties not listed in Properties.
+	PrefixItems          []*Schema          `json:"prefixItems,omitempty"`          // The schemas of the first elements of arrays, by position.
+	Items                *Schema            `json:"items,omitempty"`                // The schema of the elements of arrays not covered by PrefixItems.
+	MinItems             *int               `json:"minItems,omitempty"`             // The minimum length of arrays.
+	MaxItems             *int               `json:"maxItems,omitempty"`             // The maximum length of arrays.
+	MinLength            *int               `json:"minLength,omitempty"`            // The minimum length of strings, in characters.
+	MaxLength            *int               `json:"maxLength,omitempty"`            // The maximum length of strings, in characters.
+	Pattern              string             `json:"pattern,omitempty"`              // The regular expression strings must match.
+	Minimum              *float64           `json:"minimum,omitempty"`              // The minimum value of numbers.
+	Maximum              *float64           `json:"maximum,omitempty"`              // The maximum value of numbers.
+
+	order     []string       // The properties in the order of the fields they were derived from.
+	positions map[string]int // The positions of the properties set by jsonrpc tags, see [UnmarshalParams].
+}
+
+// MarshalJSON implements [json.Marshaler], encoding a nullable type as a list of types.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	v := struct {
+		*schema
+		Type any `json:"type,omitempty"`
+	}{schema: (*schema)(s)}
+	if s.Type != "" {
+		v.Type = s.Type
+		if s.Nullable {
+			v.Type = []string{s.Type, "null"}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], decoding a list of a type and "null" as a nullable type.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type schema Schema
+	v := struct {
+		*schema
+		Type any `json:"type,omitempty"`
+	}{schema: (*schema)(s)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch t := v.Type.(type) {
+	case string:
+		s.Type = t
+	case []any:
+		for _, e := range t {
+			if name, _ := e.(string); name == "null" {
+				s.Nullable = true
+			} else {
+				s.Type = name
+			}
+		}
+	}
+	return nil
+}
+
+// propertyNames returns the names of the properties, in field order for derived schemas and sorted otherwise.
+func (s *Schema) propertyNames() []string {
+	names := slices.Clone(s.order)
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// positional returns the names of the properties params given by position are mapped to, see [UnmarshalParams].
+// It returns nil if the schema was not derived from a struct.
+func (s *Schema) positional() []string {
+	if len(s.positions) == 0 {
+		return s.order
+	}
+	names := slices.Sorted(maps.Keys(s.positions))
+	slices.SortStableFunc(names, func(a, b string) int {
+		return s.positions[a] - s.positions[b]
+	})
+	return names
+}
+
+// SchemaFor returns the schema of the JSON encoding of values of type T.
+// Struct fields are named and omitted as by [json.Marshal]; fields without omitempty that are not pointers are required.
+// The description of a field can be set with a `description` tag.
+func SchemaFor[T any]() *Schema {
+	return schemaOf(reflect.TypeFor[T](), make(map[reflect.Type]bool))
+}
+
+var (
+	timeType            = reflect.TypeFor[time.Time]()
+	rawMessageType      = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType   = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// schemaOf returns the schema of t. Types in visiting are being described by a caller; they are described by an empty schema to stop recursion.
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Kind() == reflect.Interface, t.Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem(), visiting)
+		s.Nullable = s.Type != ""
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: t.Kind() == reflect.Slice}
+		}
+		s := &Schema{Type: "array", Items: schemaOf(t.Elem(), visiting), Nullable: t.Kind() == reflect.Slice}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			s.MinItems, s.MaxItems = &n, &n
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), visiting), Nullable: true}
+	case reflect.Struct:
+		if visiting[t] {
+			return &Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addFields(s, t, visiting)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields of the struct type t to the object schema s, flattening embedded structs as [json.Marshal] does.
+// Fields of t take precedence over the fields of embedded structs with the same name.
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		opts = "," + opts + ","
+		fs := schemaOf(ft, visiting)
+		if strings.Contains(opts, ",string,") {
+			fs = &Schema{Type: "string"}
+		}
+		fs.Description = f.Tag.Get("description")
+		s.Properties[name] = fs
+		s.order = append(s.order, name)
+		if pos, err := strconv.Atoi(f.Tag.Get("jsonrpc")); err == nil && pos >= 0 {
+			if s.positions == nil {
+				s.positions = make(map[string]int)
+			}
+			s.positions[name] = pos
+		}
+		if !strings.Contains(opts, ",omitempty,") && !strings.Contains(opts, ",omitzero,") && ft.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	for _, et := range embedded {
+		inner := &Schema{Properties: make(map[string]*Schema)}
+		addFields(inner, et, visiting)
+		for _, name := range slices.Sorted(maps.Keys(inner.Properties)) {
+			if _, exists := s.Properties[name]; !exists {
+				s.Properties[name] = inner.Properties[name]
+				s.order = append(s.order, name)
+				if pos, ok := inner.positions[name]; ok {
+					if s.positions == nil {
+						s.positions = make(map[string]int)
+					}
+					s.positions[name] = pos
+				}
+				if slices.Contains(inner.Required, name) {
+					s.Required = append(s.Required, name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes a part of a value that does not match a [Schema].
+// The Data of [InvalidParams] errors rejecting params that do not match the schema of a method is a []SchemaViolation.
+type SchemaViolation struct {
+	Pointer string `json:"pointer"` // The JSON pointer to the invalid part of the value, e.g. "/items/0/name". Empty for the whole value.
+	Reason  string `json:"reason"`  // Why the part is invalid.
+}
+
+// WithResultValidation makes a server validate the results of methods against their result schemas,
+// set with [WithResultSchema] or derived by [RegisterFunc].
+// Invalid results are replaced by an [InternalError] whose Data lists the violations.
+// As results are encoded once more, it is meant to catch bugs while debugging and testing.
+func WithResultValidation() ServerOption {
+	return func(o *serverOptions) {
+		o.validateResults = true
+	}
+}
+
+// Validate checks the JSON value data against the schema and returns the violations found, or nil if data is valid.
+func (s *Schema) Validate(data []byte) ([]SchemaViolation, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+	var violations []SchemaViolation
+	s.validate(v, "", &violations)
+	return violations, nil
+}
+
+// validate checks v, decoded with [json.Decoder.UseNumber], against the schema and appends the violations found to violations.
+func (s *Schema) validate(v any, pointer string, violations *[]SchemaViolation) {
+	if s == nil {
+		return
+	}
+	report := func(format string, args ...any) {
+		*violations = append(*violations, SchemaViolation{Pointer: pointer, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if v == nil {
+		if s.Type != "" && s.Type != "null" && !s.Nullable {
+			report("must be %s, not null", s.Type)
+		}
+		return
+	}
+	if !typeMatches(s.Type, v) {
+		report("must be %s, not %s", s.Type, jsonType(v))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return jsonEqual(e, v) }) {
+		report("must be one of %s", mustMarshal(s.Enum))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				report("missing required property %q", name)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			prop.validate(v[name], pointer+"/"+escapePointer(name), violations)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		for i, e := range v {
+			item := s.Items
+			if i < len(s.PrefixItems) {
+				item = s.PrefixItems[i]
+			}
+			item.validate(e, pointer+"/"+strconv.Itoa(i), violations)
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			report("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			report("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := compilePattern(s.Pattern)
+			if err != nil {
+				report("cannot be checked against invalid pattern %q", s.Pattern)
+			} else if !re.MatchString(v) {
+				report("must match pattern %q", s.Pattern)
+			}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			report("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			report("must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+// typeMatches reports whether v, which is not nil, is of the JSON type t. Any value matches an empty type.
+func typeMatches(t string, v any) bool {
+	switch t {
+	case "":
+		return true
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	default:
+		return jsonType(v) == t
+	}
+}
+
+// jsonType returns the JSON type of v, as decoded with [json.Decoder.UseNumber].
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// jsonEqual reports whether a and b have the same JSON encoding, ignoring the formatting of numbers and the order of properties.
+func jsonEqual(a, b any) bool {
+	var va, vb any
+	if json.Unmarshal(mustMarshal(a), &va) != nil || json.Unmarshal(mustMarshal(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mustMarshal returns the JSON encoding of v, or nil if it cannot be encoded.
+func mustMarshal(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// escapePointer escapes a reference token of a JSON pointer as described by RFC 6901.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// patterns caches the compiled regular expressions of schemas.
+var patterns sync.Map
+
+// compilePattern compiles a pattern of a schema, reusing previous compilations.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// validateParams checks the params of req against the params schema set for the method with [WithParamsSchema].
+// It returns the response rejecting req, or nil if the params are valid.
+// Missing params are checked as an empty object or array, so that required properties are enforced.
+// Params given by position for a schema derived from a struct are checked as the object they are decoded to.
+func (m *MethodInfo) validateParams(req *Request) *Response {
+	if m == nil || m.Params == nil {
+		return nil
+	}
+	params := []byte(req.Params)
+	if len(params) == 0 {
+		switch m.Params.Type {
+		case "object":
+			params = []byte("{}")
+		case "array":
+			params = []byte("[]")
+		default:
+			return nil
+		}
+	}
+
+	if names := m.Params.positional(); names != nil && m.Params.Type == "object" && bytes.HasPrefix(bytes.TrimSpace(params), []byte("[")) {
+		named, err := positionalToNamed(params, names)
+		if err != nil {
+			return newErrorResponse(req.ID, InvalidParams, "Invalid params", WithData([]SchemaViolation{{Reason: err.Error()}}))
+		}
+		params = named
+	}
+
+	violations, err := m.Params.Validate(params)
+	if err != nil {
+		return newErrorResponse(req.ID, InvalidParams, "Invalid params")
+	}
+	if len(violations) > 0 {
+		return newErrorResponse(req.ID, InvalidParams, "Invalid params", WithData(violations))
+	}
+	return nil
+}
+
+// validateResult checks the result of resp against the result schema of the method.
+// It returns an internal error response listing the violations, or resp if the result is valid.
+func (m *MethodInfo) validateResult(resp *Response) *Response {
+	if m == nil || resp.Error != nil {
+		return resp
+	}
+	schema := m.resultSchema()
+	if schema == nil {
+		return resp
+	}
+	data, err := json.Marshal(resp.Result)
+	if err != nil {
+		return newErrorResponse(resp.ID, InternalError, "Internal error")
+	}
+	violations, err := schema.Validate(data)
+	if err != nil || len(violations) > 0 {
+		return newErrorResponse(resp.ID, InternalError, "Invalid result", WithData(violations))
+	}
+	return resp
+}
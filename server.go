@@ -0,0 +1,339 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ServerOption defines a function type for setting optional fields of a [Server].
+// Each option names the servers it configures, such as [HTTPServer] for [WithHTTPStatusCodes], or applies to all servers
+// if it says "a server"; other servers ignore it.
+type ServerOption func(*serverOptions)
+
+// serverOptions holds the settings configured by [ServerOption].
+type serverOptions struct {
+	httpStatusCodes map[ErrorCode]int
+	httpGetMethods  map[string]HTTPCachePolicy
+	pingInterval    time.Duration
+	checkOrigin     func(r *http.Request) bool
+	connHandlers    int
+	network         string
+	stdin           io.Reader
+	stdout          io.Writer
+	listener        net.Listener
+	socketMode      os.FileMode
+	eventStream     bool
+	tlsConfig       *tls.Config
+	authenticator   Authenticator
+	loginMethod     string
+	authError       *Error
+	authorizer      Authorizer
+	maxHandlers     int
+	maxConns        int
+	methodLimits    map[string]RateLimit
+	connLimit       *RateLimit
+	peerLimit       *RateLimit
+	maxMessageSize  int64
+	maxBatchSize    int
+	maxDepth        int
+	maxStringLength int
+	openRPCInfo     OpenRPCInfo
+	noDiscovery     bool
+	validateResults bool
+	useNumber       bool
+	tap             *Tap
+	middleware      []Middleware
+	metrics         Metrics
+
+	handshakeTimeout time.Duration
+}
+
+// newServerOptions applies opts on top of the default settings.
+func newServerOptions(opts []ServerOption) *serverOptions {
+	o := &serverOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Notifier sends notifications to the client a request was received from.
+type Notifier interface {
+	// Notify sends a JSON-RPC 2.0 notification to the client. The ID of req, if any, is not sent.
+	Notify(ctx context.Context, req *Request) error
+}
+
+// notifierKey is the context key for the [Notifier] of the current connection.
+type notifierKey struct{}
+
+// NotifierFromContext returns the [Notifier] of the connection a request was received on.
+// It is available to handlers of transports that can push messages to the client, such as [WebSocketServer],
+// and of an [HTTPServer] streaming the response, see [WithEventStream].
+// The notifier may be kept to push notifications after the handler returned, until the connection is closed;
+// over HTTP, notifications fail once the response has been sent.
+func NotifierFromContext(ctx context.Context) (Notifier, bool) {
+	n, ok := ctx.Value(notifierKey{}).(Notifier)
+	return n, ok
+}
+
+// contextWithNotifier returns a copy of ctx carrying n.
+func contextWithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// messageNotifier is a [Notifier] that writes to a message-oriented connection.
+type messageNotifier struct {
+	conn interface {
+		writeMessage(ctx context.Context, data []byte) error
+	}
+}
+
+// Notify implements [Notifier].
+func (n messageNotifier) Notify(ctx context.Context, req *Request) error {
+	data, err := json.Marshal(req.asNotification())
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	if err := n.conn.writeMessage(ctx, data); err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
+
+// writeLine writes reply to w as a single line, recording it in tap.
+// If reply cannot be encoded, an internal error response is written and the encoding error returned.
+func writeLine(w io.Writer, tap *tapSession, reply any) error {
+	data, err := marshalReply(reply)
+	tap.record(TapSend, data)
+	if _, writeErr := w.Write(append(data, '\n')); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// dispatcher routes JSON-RPC messages to registered handlers.
+// It is shared by all server transports.
+type dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	methods  map[string]*MethodInfo
+
+	openRPCInfo     OpenRPCInfo
+	noDiscovery     bool
+	validateResults bool
+	useNumber       bool
+
+	authenticator Authenticator
+	loginMethod   string
+	authError     *Error
+	authorizer    Authorizer
+	limiter       *limiter
+
+	maxMessageSize  int64
+	maxBatchSize    int
+	maxDepth        int
+	maxStringLength int
+
+	tap     *Tap
+	metrics Metrics
+	serve   Handler // Handles a request through the middleware, see handle.
+}
+
+// newDispatcher creates a new [dispatcher] with an empty handlers, applying the settings of o.
+func newDispatcher(o *serverOptions) *dispatcher {
+	d := &dispatcher{
+		handlers:    make(map[string]Handler),
+		methods:     make(map[string]*MethodInfo),
+		openRPCInfo: o.openRPCInfo,
+		noDiscovery: o.noDiscovery,
+
+		validateResults: o.validateResults,
+		useNumber:       o.useNumber,
+		authenticator:   o.authenticator,
+		loginMethod:     o.loginMethod,
+		authError:       o.authError,
+		authorizer:      o.authorizer,
+		limiter:         newLimiter(o),
+
+		maxMessageSize:  o.maxMessageSize,
+		maxBatchSize:    o.maxBatchSize,
+		maxDepth:        o.maxDepth,
+		maxStringLength: o.maxStringLength,
+
+		tap:     o.tap,
+		metrics: o.metrics,
+	}
+	mws := o.middleware
+	if o.metrics != nil {
+		mws = append([]Middleware{d.measure(o.metrics)}, mws...)
+	}
+	d.serve = chainMiddleware(d.dispatch, mws)
+	return d
+}
+
+// Register registers a handler for a specific method.
+func (d *dispatcher) Register(method string, handler Handler) {
+	d.RegisterMethod(method, handler)
+}
+
+// RegisterMethod registers a handler for a specific method, described by opts.
+func (d *dispatcher) RegisterMethod(method string, handler Handler, opts ...RegisterOption) {
+	info := &MethodInfo{}
+	for _, opt := range opts {
+		opt(info)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[method] = handler
+	d.methods[method] = info
+}
+
+// handler returns the handler registered for method and its description.
+func (d *dispatcher) handler(method string) (Handler, *MethodInfo, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	handler, ok := d.handlers[method]
+	return handler, d.methods[method], ok
+}
+
+// known reports whether d answers method, either with a registered handler or with the discovery document.
+func (d *dispatcher) known(method string) bool {
+	_, _, ok := d.handler(method)
+	return ok || (method == discoverMethod && !d.noDiscovery)
+}
+
+// dispatcherKey is the context key for the [dispatcher] handling a request.
+type dispatcherKey struct{}
+
+// KnownMethod reports whether the server handling the request ctx was passed with answers method,
+// rather than with a [MethodNotFound] error. Middleware can use it, as the server does with [Metrics],
+// to avoid recording the arbitrary method names clients may send.
+func KnownMethod(ctx context.Context, method string) bool {
+	d, ok := ctx.Value(dispatcherKey{}).(*dispatcher)
+	return ok && d.known(method)
+}
+
+// handleMessage processes a single request or a batch of requests encoded in data.
+// It returns the *Response or []*Response to send back, or nil if nothing must be sent,
+// which is the case for notifications and batches made only of notifications.
+func (d *dispatcher) handleMessage(ctx context.Context, data []byte) any {
+	data = bytes.TrimSpace(data)
+	if resp := d.checkStructure(data); resp != nil {
+		return d.reject(resp)
+	}
+	if !json.Valid(data) {
+		return d.reject(newErrorResponse(nil, ParseError, "Parse error"))
+	}
+	if data[0] != '[' {
+		if resp := d.handleRaw(ctx, data); resp != nil {
+			return resp
+		}
+		return nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil || len(batch) == 0 {
+		return d.reject(newErrorResponse(nil, InvalidRequest, "Invalid Request"))
+	}
+	if d.maxBatchSize > 0 && len(batch) > d.maxBatchSize {
+		return d.reject(tooLarge("batch"))
+	}
+	if d.metrics != nil {
+		d.metrics.Batch(len(batch))
+	}
+	var resps []*Response
+	for _, raw := range batch {
+		if resp := d.handleRaw(ctx, raw); resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		return nil
+	}
+	return resps
+}
+
+// handleRaw decodes and processes a single request. It returns nil for notifications.
+// Numeric IDs are decoded as float64, or as [json.Number] with [WithServerUseNumber].
+func (d *dispatcher) handleRaw(ctx context.Context, raw json.RawMessage) *Response {
+	var req Request
+	if err := req.unmarshal(raw, d.useNumber); err != nil || req.JSONRPC != version || req.Method == "" {
+		return d.reject(newErrorResponse(nil, InvalidRequest, "Invalid Request"))
+	}
+	switch req.ID.(type) {
+	case nil, string, float64, json.Number:
+	default:
+		return d.reject(newErrorResponse(nil, InvalidRequest, "Invalid Request"))
+	}
+	return d.handle(ctx, &req)
+}
+
+// handle processes a single decoded request through the middleware. It returns nil for notifications.
+func (d *dispatcher) handle(ctx context.Context, req *Request) *Response {
+	ctx = context.WithValue(ctx, dispatcherKey{}, d)
+	resp := d.serve(ctx, req)
+	if req.IsNotification() {
+		return nil
+	}
+	return resp
+}
+
+// dispatch checks the limits and authorization of req and calls its handler, or logs in its session.
+// Requests are authorized before the method and concurrency limits are checked, so that unauthenticated clients
+// only use up the limits of their connection and address.
+func (d *dispatcher) dispatch(ctx context.Context, req *Request) *Response {
+	if resp := d.limiter.admit(ctx, req); resp != nil {
+		return resp
+	}
+	handle := d.call
+	if s, ok := d.loginSession(ctx, req); ok {
+		handle = func(ctx context.Context, req *Request) *Response { return d.login(ctx, s, req) }
+	} else if resp := d.authorize(ctx, req); resp != nil {
+		return resp
+	}
+
+	release, resp := d.limiter.acquire(ctx, req)
+	if resp != nil {
+		return resp
+	}
+	defer release()
+	return handle(ctx, req)
+}
+
+// call calls the handler registered for the method of req.
+func (d *dispatcher) call(ctx context.Context, req *Request) *Response {
+	handler, info, exists := d.handler(req.Method)
+	if !exists && req.Method == discoverMethod && !d.noDiscovery {
+		handler, exists = d.handleDiscover, true
+	}
+	if !exists {
+		return newErrorResponse(req.ID, MethodNotFound, "Method not found")
+	}
+	if resp := info.validateParams(req); resp != nil {
+		return resp
+	}
+
+	resp := handler(ctx, req)
+	if resp == nil {
+		return newErrorResponse(req.ID, InternalError, "Internal error")
+	}
+	if d.validateResults {
+		resp = info.validateResult(resp)
+	}
+	return resp
+}
+
+// newErrorResponse creates a new [Response] carrying an [Error].
+func newErrorResponse(id any, code ErrorCode, message string, opts ...NewErrorOption) *Response {
+	return NewResponse(id, WithError(*NewError(code, message, opts...)))
+}
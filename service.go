@@ -0,0 +1,242 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ServiceOption defines a function type for setting optional fields of [RegisterService].
+type ServiceOption func(*serviceOptions)
+
+// serviceOptions holds the settings configured by [ServiceOption].
+type serviceOptions struct {
+	nameMapper func(string) string
+	skip       func(name string) bool
+	methodOpts map[string][]RegisterOption
+}
+
+// WithNameMapper sets the function mapping the Go names of the methods of a service to method names, e.g. [SnakeCase].
+// It defaults to [CamelCase].
+func WithNameMapper(mapper func(string) string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.nameMapper = mapper
+	}
+}
+
+// WithSkipMethods excludes the methods of a service with the given Go names.
+func WithSkipMethods(names ...string) ServiceOption {
+	return func(o *serviceOptions) {
+		prev := o.skip
+		o.skip = func(name string) bool {
+			return slices.Contains(names, name) || (prev != nil && prev(name))
+		}
+	}
+}
+
+// WithMethodFilter excludes the methods of a service whose Go names are rejected by include.
+func WithMethodFilter(include func(name string) bool) ServiceOption {
+	return func(o *serviceOptions) {
+		prev := o.skip
+		o.skip = func(name string) bool {
+			return !include(name) || (prev != nil && prev(name))
+		}
+	}
+}
+
+// WithMethodOptions describes the method of a service with the given Go name, see [RegisterOption].
+func WithMethodOptions(name string, opts ...RegisterOption) ServiceOption {
+	return func(o *serviceOptions) {
+		if o.methodOpts == nil {
+			o.methodOpts = make(map[string][]RegisterOption)
+		}
+		o.methodOpts[name] = append(o.methodOpts[name], opts...)
+	}
+}
+
+// CamelCase maps a Go method name to a method name starting with a lower case letter, e.g. "GetUser" to "getUser".
+// A leading initialism is lowered as a whole, e.g. "HTTPStatus" to "httpStatus".
+func CamelCase(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// SnakeCase maps a Go method name to a lower case method name whose words are separated by underscores,
+// e.g. "GetUser" to "get_user" and "HTTPStatus" to "http_status".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// RegisterService registers the exported methods of svc on s, under names made of prefix, a dot and the mapped Go name,
+// or only the mapped Go name if prefix is empty. Methods may take a [context.Context] first and must return an error last,
+// optionally preceded by a result, e.g.
+//
+//	func (s *Service) Add(ctx context.Context, params AddParams) (int, error)
+//	func (s *Service) Move(ctx context.Context, x, y int, speed *float64) error
+//
+// A method with a single param encoded as a JSON object, such as a struct or a map, is given the params as sent,
+// by name or by position, decoded as by [Func]. A method with any other params takes them by position from an array,
+// e.g. [5] for a single int; trailing pointer params are optional.
+// Methods of other shapes are skipped. If s is a [Describer], the schemas of params and results derived from the Go types
+// are published by rpc.discover, as by [RegisterFunc].
+func RegisterService(s Server, prefix string, svc any, opts ...ServiceOption) error {
+	o := &serviceOptions{nameMapper: CamelCase}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	v := reflect.ValueOf(svc)
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return errors.New("service must not be nil")
+	}
+
+	registered := 0
+	for i := range v.NumMethod() {
+		m := v.Type().Method(i)
+		if o.skip != nil && o.skip(m.Name) {
+			continue
+		}
+		h, info, ok := serviceMethod(v.Method(i))
+		if !ok {
+			continue
+		}
+
+		name := o.nameMapper(m.Name)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		regOpts := []RegisterOption{withDerivedSchemas(info.Params, info.Result)}
+		registerMethod(s, name, h, append(regOpts, o.methodOpts[m.Name]...))
+		registered++
+	}
+	if registered == 0 {
+		return fmt.Errorf("service %s has no exported method of a supported shape", v.Type())
+	}
+	return nil
+}
+
+// serviceMethod returns a handler calling the method fn of a service and its schemas, and reports whether fn has a supported shape.
+func serviceMethod(fn reflect.Value) (Handler, *MethodInfo, bool) {
+	t := fn.Type()
+	if t.IsVariadic() || t.NumOut() == 0 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		return nil, nil, false
+	}
+	withCtx := t.NumIn() > 0 && t.In(0) == contextType
+	var params []reflect.Type
+	for i := range t.NumIn() {
+		if i == 0 && withCtx {
+			continue
+		}
+		params = append(params, t.In(i))
+	}
+
+	info := &MethodInfo{}
+	visiting := make(map[reflect.Type]bool)
+	object := len(params) == 1 && schemaOf(params[0], visiting).Type == "object"
+	switch {
+	case len(params) == 0:
+	case object:
+		info.Params = schemaOf(params[0], visiting)
+	default:
+		info.Params = &Schema{Type: "array"}
+		required := 0
+		for i, p := range params {
+			info.Params.PrefixItems = append(info.Params.PrefixItems, schemaOf(p, visiting))
+			if p.Kind() != reflect.Pointer {
+				required = i + 1
+			}
+		}
+		total := len(params)
+		info.Params.MinItems, info.Params.MaxItems = &required, &total
+	}
+	if t.NumOut() == 2 {
+		info.Result = schemaOf(t.Out(0), visiting)
+	}
+
+	handler := func(ctx context.Context, req *Request) *Response {
+		args, err := decodeArgs(req.Params, params, object)
+		if err != nil {
+			return newErrorResponse(req.ID, InvalidParams, "Invalid params", WithData(err.Error()))
+		}
+		if withCtx {
+			args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+		}
+
+		out := fn.Call(args)
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return NewResponse(req.ID, WithError(AsError(err)))
+		}
+		// Methods returning only an error answer with a null result.
+		var result any = json.RawMessage("null")
+		if len(out) == 2 {
+			result = out[0].Interface()
+		}
+		return NewResponse(req.ID, WithResult(result))
+	}
+	return handler, info, true
+}
+
+// decodeArgs decodes params into values of the given types.
+// A single type encoded as an object is decoded as by [UnmarshalParams]; other types are decoded by position from an array.
+func decodeArgs(params json.RawMessage, types []reflect.Type, object bool) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(types))
+	for i, t := range types {
+		args[i] = reflect.New(t)
+	}
+	hasParams := len(params) > 0 && string(params) != "null"
+
+	switch {
+	case object && hasParams:
+		if err := UnmarshalParams(params, args[0].Interface()); err != nil {
+			return nil, err
+		}
+	case hasParams:
+		var raw []json.RawMessage
+		if err := json.Unmarshal(params, &raw); err != nil {
+			return nil, fmt.Errorf("params must be an array of %d values: %w", len(types), err)
+		}
+		if len(raw) > len(types) {
+			return nil, fmt.Errorf("params must be an array of at most %d values", len(types))
+		}
+		for i, r := range raw {
+			if err := json.Unmarshal(r, args[i].Interface()); err != nil {
+				return nil, fmt.Errorf("invalid param %d: %w", i, err)
+			}
+		}
+	}
+
+	for i := range args {
+		args[i] = args[i].Elem()
+	}
+	return args, nil
+}
@@ -0,0 +1,165 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const eventStreamContentType = "text/event-stream"
+
+// errStreamClosed is returned when notifying through an event stream whose response was already sent.
+var errStreamClosed = errors.New("event stream closed")
+
+// WithEventStream makes an [HTTPServer] stream responses as server-sent events to clients that accept text/event-stream.
+// Notifications that handlers send through the [Notifier] returned by [NotifierFromContext] are streamed as events
+// while the request is processed, followed by the response as the last event.
+// If a handler sends no notification and the client also accepts application/json, a plain JSON response is sent.
+func WithEventStream() ServerOption {
+	return func(o *serverOptions) {
+		o.eventStream = true
+	}
+}
+
+// eventStream is a [Notifier] that writes messages to an HTTP response as server-sent events.
+// The stream is started by the first message, so requests that send no notification can still be answered with plain JSON.
+type eventStream struct {
+	w      http.ResponseWriter
+	force  bool // Whether the response must be streamed even if no notification was sent.
+	mu     sync.Mutex
+	start  bool
+	closed bool
+}
+
+// newEventStream creates a new [eventStream] writing to w.
+func newEventStream(w http.ResponseWriter, force bool) *eventStream {
+	return &eventStream{
+		w:     w,
+		force: force,
+	}
+}
+
+// Notify implements [Notifier].
+func (s *eventStream) Notify(ctx context.Context, req *Request) error {
+	data, err := json.Marshal(req.asNotification())
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errStreamClosed
+	}
+	return s.writeEvent(data)
+}
+
+// finish closes the stream to further notifications and, if the response is streamed, sends reply as the last event.
+// It reports whether the response was written, in which case the stream ends without event if there is no reply.
+func (s *eventStream) finish(reply any) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if reply == nil {
+		return s.start
+	}
+	if !s.start && !s.force {
+		return false
+	}
+
+	data, _ := marshalReply(reply)
+	s.writeEvent(data)
+	return true
+}
+
+// writeEvent writes data as a message event, starting the stream if needed. The caller must hold s.mu.
+func (s *eventStream) writeEvent(data []byte) error {
+	if !s.start {
+		s.w.Header().Set("Content-Type", eventStreamContentType)
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+		s.start = true
+	}
+	if _, err := fmt.Fprintf(s.w, "event: message\ndata: %s\n\n", data); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return http.NewResponseController(s.w).Flush()
+}
+
+// acceptsEventStream reports whether the Accept header values allow server-sent events.
+func acceptsEventStream(accept []string) bool {
+	for _, v := range accept {
+		for _, mediaRange := range strings.Split(v, ",") {
+			mediaType, _, err := mime.ParseMediaType(mediaRange)
+			if err == nil && mediaType == eventStreamContentType && !hasZeroQuality(mediaRange) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readReply reads the JSON-RPC response carried by the body of resp.
+// If the response is streamed as server-sent events, the requests preceding the response are passed to the client's notification handler.
+// The messages read are recorded in tap.
+func (c *HTTPClient) readReply(ctx context.Context, resp *http.Response, tap *tapSession) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != eventStreamContentType {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if int64(len(data)) > c.maxSize {
+			return nil, fmt.Errorf("response exceeds %d bytes", c.maxSize)
+		}
+		tap.record(TapReceive, data)
+		return data, nil
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, int(c.maxSize))
+	var data []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		if field, value, ok := strings.Cut(line, ":"); ok && field == "data" {
+			data = append(data, strings.TrimPrefix(value, " ")...)
+			data = append(data, '\n')
+			if int64(len(data)) > c.maxSize {
+				return nil, fmt.Errorf("event exceeds %d bytes", c.maxSize)
+			}
+			continue
+		}
+		if line != "" || len(data) == 0 {
+			// Other fields, comments and empty events are ignored.
+			continue
+		}
+
+		msg := bytes.TrimSpace(data)
+		data = nil
+		if len(msg) == 0 {
+			continue
+		}
+		tap.record(TapReceive, msg)
+		var env messageEnvelope
+		if msg[0] == '{' && json.Unmarshal(msg, &env) == nil && env.Method != "" {
+			var req Request
+			if c.onNotify != nil && json.Unmarshal(msg, &req) == nil {
+				c.onNotify(ctx, &req)
+			}
+			continue
+		}
+		return msg, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return nil, fmt.Errorf("event stream ended without response: %w", io.ErrUnexpectedEOF)
+}
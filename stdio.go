@@ -2,59 +2,89 @@ package jsonrpc2
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"encoding/json"
+	"io"
 	"log"
 	"os"
 )
 
 // StdioServer is a JSON-RPC 2.0 server that reads requests from standard input and writes responses to standard output.
 type StdioServer struct {
-	handlers map[string]Handler
+	*dispatcher
+	in  io.Reader
+	out io.Writer
 }
 
 // NewStdioServer creates a new [StdioServer] with an empty handlers.
-func NewStdioServer() *StdioServer {
-	return &StdioServer{
-		handlers: make(map[string]Handler),
+// Use [WithStdio] to serve other streams than the standard ones.
+func NewStdioServer(opts ...ServerOption) *StdioServer {
+	o := newServerOptions(opts)
+	s := &StdioServer{
+		dispatcher: newDispatcher(o),
+		in:         o.stdin,
+		out:        o.stdout,
+	}
+	if s.in == nil {
+		s.in = os.Stdin
+	}
+	if s.out == nil {
+		s.out = os.Stdout
+	}
+	return s
+}
+
+// WithStdio makes a [StdioServer] read requests from in and write responses to out, e.g. the ends of an [io.Pipe]
+// or a [net.Pipe] in tests, instead of standard input and output.
+func WithStdio(in io.Reader, out io.Writer) ServerOption {
+	return func(o *serverOptions) {
+		o.stdin = in
+		o.stdout = out
 	}
 }
 
-var _ Server = (*StdioServer)(nil)
+var _ Describer = (*StdioServer)(nil)
 
 // Register registers a handler for a specific method.
 func (s *StdioServer) Register(method string, handler Handler) {
-	s.handlers[method] = handler
+	s.dispatcher.Register(method, handler)
+}
+
+// RegisterMethod registers a handler for a specific method.
+// The options describe the method in the document served by rpc.discover, see [RegisterOption].
+func (s *StdioServer) RegisterMethod(method string, handler Handler, opts ...RegisterOption) {
+	s.dispatcher.RegisterMethod(method, handler, opts...)
 }
 
 // Run starts the server, reading requests from standard input and writing responses to standard output.
+// It returns once the input is exhausted.
 func (s *StdioServer) Run(ctx context.Context) error {
-	scanner := bufio.NewScanner(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(s.in)
+	s.setScannerLimit(scanner)
+	tap := s.tap.open(TapServer, "stdio", "")
+	defer openConn(s.metrics, "stdio")()
 
 	log.Println("JSON-RPC 2.0 stdio server started")
 
+	// Without credentials to check, the authenticator may only accept the process itself, e.g. from its environment.
+	ctx, _ = s.startSession(ctx, &Credentials{})
+
 	for scanner.Scan() {
-		line := scanner.Text()
-		var req Request
-		if err := json.Unmarshal([]byte(line), &req); err != nil {
-			log.Printf("Error unmarshalling request: %v", err)
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		if handler, exists := s.handlers[req.Method]; exists {
-			resp := handler(ctx, &req)
-			if err := encoder.Encode(resp); err != nil {
+		tap.record(TapReceive, line)
+		if reply := s.handleMessage(ctx, line); reply != nil {
+			if err := writeLine(s.out, tap, reply); err != nil {
 				log.Printf("Error encoding response: %v", err)
 			}
-		} else {
-			err := NewError(MethodNotFound, "Method not found")
-			resp := NewResponse(req.ID, WithError(*err))
-			if err := encoder.Encode(resp); err != nil {
-				log.Printf("Error encoding error response: %v", err)
-			}
 		}
 	}
 
+	if resp := scanError(scanner.Err()); resp != nil {
+		writeLine(s.out, tap, resp)
+	}
 	return scanner.Err()
 }
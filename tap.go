@@ -0,0 +1,290 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tap records the messages a client or server sends and receives, as they appear on the wire, to a writer.
+// Each message is written as a [TapEvent] encoded on a single line (JSON Lines), e.g.
+//
+//	f, _ := os.Create("session.jsonl")
+//	tap := jsonrpc2.NewTap(f)
+//	client := jsonrpc2.NewTCPClientWithDialer(dialer, jsonrpc2.WithTap(tap))
+//
+// A session recorded this way can be sent again with [Replay]. A Tap is safe for concurrent use
+// and may be shared by several clients and servers.
+type Tap struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+
+	conns atomic.Uint64
+}
+
+// NewTap creates a new [Tap] writing events to w.
+func NewTap(w io.Writer) *Tap {
+	return &Tap{w: w}
+}
+
+// Err returns the first error writing an event failed with, if any. Events are dropped once writing failed.
+func (t *Tap) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// WithTap makes a client record the messages it exchanges with t.
+// Calls an [HTTPClient] sends with GET are recorded as the request encoded in the URL.
+func WithTap(t *Tap) ClientOption {
+	return func(o *clientOptions) {
+		o.tap = t
+	}
+}
+
+// WithServerTap makes a server record the messages it exchanges with t.
+// HTTP requests rejected before their body is read, e.g. for their Content-Type, are not recorded.
+func WithServerTap(t *Tap) ServerOption {
+	return func(o *serverOptions) {
+		o.tap = t
+	}
+}
+
+// TapRole is the side of the connection a [TapEvent] was recorded on.
+type TapRole string
+
+const (
+	TapClient TapRole = "client" // The event was recorded by a client.
+	TapServer TapRole = "server" // The event was recorded by a server.
+)
+
+// TapDirection tells whether the message of a [TapEvent] was sent or received.
+type TapDirection string
+
+const (
+	TapSend    TapDirection = "send"    // The message was sent to the peer.
+	TapReceive TapDirection = "receive" // The message was received from the peer.
+)
+
+// TapEvent is a message recorded by a [Tap].
+type TapEvent struct {
+	Time      time.Time       `json:"time"`                 // When the message was sent or received.
+	Role      TapRole         `json:"role"`                 // Whether a client or a server recorded the message.
+	Transport string          `json:"transport"`            // The transport, e.g. "http", "websocket", "tcp", "unix" or "stdio".
+	Conn      uint64          `json:"conn"`                 // The ID of the connection, unique within the tap. Each HTTP request is a connection of its own.
+	Remote    string          `json:"remote,omitempty"`     // The address of the peer, if known.
+	Direction TapDirection    `json:"direction"`            // Whether the message was sent or received.
+	Latency   time.Duration   `json:"latency_ns,omitempty"` // For replies, the time since their requests were seen on the connection.
+	Message   json.RawMessage `json:"message,omitempty"`    // The message, compacted.
+	Invalid   string          `json:"invalid,omitempty"`    // The data received or sent instead of a message if it is not valid JSON.
+}
+
+// ReadTap reads the events written by a [Tap].
+func ReadTap(r io.Reader) ([]TapEvent, error) {
+	var events []TapEvent
+	dec := json.NewDecoder(r)
+	for {
+		var e TapEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return events, fmt.Errorf("failed to decode tap event %d: %w", len(events)+1, err)
+		}
+		events = append(events, e)
+	}
+}
+
+// open starts recording a connection of transport with the peer at remote.
+// It returns nil, which records nothing, if t is nil.
+func (t *Tap) open(role TapRole, transport, remote string) *tapSession {
+	if t == nil {
+		return nil
+	}
+	return &tapSession{
+		tap:       t,
+		role:      role,
+		transport: transport,
+		remote:    remote,
+		conn:      t.conns.Add(1),
+		pending:   make(map[string]time.Time),
+	}
+}
+
+// openConn starts recording conn, see [Tap.open].
+func (t *Tap) openConn(role TapRole, conn net.Conn) *tapSession {
+	if t == nil {
+		return nil
+	}
+	transport, remote := "", ""
+	if addr := conn.RemoteAddr(); addr != nil {
+		transport, remote = addr.Network(), addr.String()
+	}
+	return t.open(role, transport, remote)
+}
+
+// write writes e as a line.
+func (t *Tap) write(e *TapEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		t.err = fmt.Errorf("failed to write tap event: %w", err)
+	}
+}
+
+// tapSession records the messages of a connection.
+type tapSession struct {
+	tap       *Tap
+	role      TapRole
+	transport string
+	remote    string
+	conn      uint64
+
+	mu      sync.Mutex
+	pending map[string]time.Time // When the requests awaiting a reply were seen, by ID key.
+}
+
+// record records data sent or received. A nil session records nothing.
+func (s *tapSession) record(dir TapDirection, data []byte) {
+	if s == nil {
+		return
+	}
+	data = bytes.TrimSpace(data)
+	e := &TapEvent{
+		Time:      time.Now(),
+		Role:      s.role,
+		Transport: s.transport,
+		Conn:      s.conn,
+		Remote:    s.remote,
+		Direction: dir,
+	}
+	if json.Valid(data) {
+		e.Message = data
+		e.Latency = s.latency(e.Time, data)
+	} else {
+		e.Invalid = string(data)
+	}
+	s.tap.write(e)
+}
+
+// latency remembers when the requests of data were seen and returns the time since the requests it replies to were,
+// or 0 if data is not a reply.
+func (s *tapSession) latency(now time.Time, data []byte) time.Duration {
+	envelopes, err := messageEnvelopes(data)
+	if err != nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latency time.Duration
+	for _, env := range envelopes {
+		if !env.hasID() {
+			continue
+		}
+		key := env.key()
+		if env.Method != "" {
+			s.pending[key] = now
+			continue
+		}
+		if seen, ok := s.pending[key]; ok {
+			delete(s.pending, key)
+			latency = max(latency, now.Sub(seen))
+		}
+	}
+	return latency
+}
+
+// messageEnvelopes decodes the envelopes of a message or batch, as a batch of one for a single message.
+func messageEnvelopes(data []byte) ([]messageEnvelope, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var envelopes []messageEnvelope
+		if err := json.Unmarshal(data, &envelopes); err != nil {
+			return nil, err
+		}
+		return envelopes, nil
+	}
+	var env messageEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	return []messageEnvelope{env}, nil
+}
+
+// tapMessages returns conn recording the messages it carries in s, or conn itself if s is nil.
+func tapMessages(conn messageConn, s *tapSession) messageConn {
+	if s == nil {
+		return conn
+	}
+	return &tapConn{messageConn: conn, session: s}
+}
+
+// tapConn is a [messageConn] recording the messages it carries.
+type tapConn struct {
+	messageConn
+	session *tapSession
+}
+
+func (c *tapConn) readMessage() ([]byte, error) {
+	data, err := c.messageConn.readMessage()
+	if err == nil {
+		c.session.record(TapReceive, data)
+	}
+	return data, err
+}
+
+// writeMessage records data before writing it, so that replies are never recorded before their request.
+func (c *tapConn) writeMessage(ctx context.Context, data []byte) error {
+	c.session.record(TapSend, data)
+	return c.messageConn.writeMessage(ctx, data)
+}
+
+// tapResponseWriter is an [http.ResponseWriter] recording the JSON-RPC messages of the response,
+// whether written as a JSON body or as server-sent events.
+type tapResponseWriter struct {
+	http.ResponseWriter
+	session *tapSession
+}
+
+// tapResponses returns w recording the messages of the response in s, or w itself if s is nil.
+func tapResponses(w http.ResponseWriter, s *tapSession) http.ResponseWriter {
+	if s == nil {
+		return w
+	}
+	return &tapResponseWriter{ResponseWriter: w, session: s}
+}
+
+// Write records p, which is a whole JSON body or event as the server writes them in one go.
+func (w *tapResponseWriter) Write(p []byte) (int, error) {
+	if w.Header().Get("Content-Type") != eventStreamContentType {
+		w.session.record(TapSend, p)
+		return w.ResponseWriter.Write(p)
+	}
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+			w.session.record(TapSend, data)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *tapResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -2,32 +2,104 @@ package jsonrpc2
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"sync"
+	"time"
 )
 
 // TCPClient is a JSON-RPC 2.0 client that communicates over TCP.
+// It is safe for concurrent use; concurrent calls are pipelined over the connections of its pool, see [WithPoolSize].
 type TCPClient struct {
-	conn net.Conn
+	pool     *connPool
+	decoding resultDecoding
+	metrics  Metrics
 }
 
-// NewTCPClient creates a new [TCPClient].
-func NewTCPClient(conn net.Conn) *TCPClient {
+// NewTCPClient creates a new [TCPClient] that uses a single pre-dialed connection.
+// The client cannot recover once conn is closed; use [NewTCPClientWithDialer] for automatic reconnects.
+func NewTCPClient(conn net.Conn, opts ...ClientOption) *TCPClient {
+	o := newClientOptions(opts)
+	pool := newConnPool(nil, o)
+	pool.slots[0].setConn(conn)
 	return &TCPClient{
-		conn: conn,
+		pool:     pool,
+		decoding: o.resultDecoding,
+		metrics:  o.metrics,
+	}
+}
+
+// NewTCPClientWithDialer creates a new [TCPClient] that opens connections on demand using dialer.
+// Connections that fail are redialed by later calls, waiting according to the backoff set with [WithReconnectBackoff].
+// Use [WithPoolSize] to spread concurrent calls over several connections.
+func NewTCPClientWithDialer(dialer Dialer, opts ...ClientOption) *TCPClient {
+	o := newClientOptions(opts)
+	return &TCPClient{
+		pool:     newConnPool(dialer, o),
+		decoding: o.resultDecoding,
+		metrics:  o.metrics,
+	}
+}
+
+// Dialer is a function type that opens a new connection to a JSON-RPC 2.0 server.
+type Dialer func(ctx context.Context) (net.Conn, error)
+
+// TCPDialer returns a [Dialer] that connects to addr over TCP.
+func TCPDialer(addr string) Dialer {
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// WithPoolSize sets the maximum number of connections a [TCPClient] created by [NewTCPClientWithDialer] keeps open.
+// Calls are spread over the connections by the number of calls in flight, so that a single connection serves sequential calls.
+func WithPoolSize(size int) ClientOption {
+	return func(o *clientOptions) {
+		if size > 0 {
+			o.poolSize = size
+		}
+	}
+}
+
+// WithReconnectBackoff sets the backoff a [TCPClient] waits between consecutive failed dials of a connection.
+func WithReconnectBackoff(backoff Backoff) ClientOption {
+	return func(o *clientOptions) {
+		o.backoff = backoff
+	}
+}
+
+// defaultMaxClientMessageSize is the maximum size of a message read by a client, see [WithClientMaxMessageSize].
+const defaultMaxClientMessageSize = 32 << 20
+
+// WithClientMaxMessageSize limits the size in bytes of the messages a [TCPClient], [WebSocketClient] or [HTTPClient] reads.
+// It defaults to 32 MiB. A stream connection sending a longer message is closed, failing the calls in flight,
+// as the following messages cannot be found; an HTTP response or event over the limit fails its call.
+func WithClientMaxMessageSize(n int64) ClientOption {
+	return func(o *clientOptions) {
+		if n > 0 {
+			o.maxMessageSize = n
+		}
 	}
 }
 
 var _ Client = (*TCPClient)(nil)
 
+var _ BatchCaller = (*TCPClient)(nil)
+
 // Call sends a JSON-RPC 2.0 request over TCP and returns the response.
-func (c *TCPClient) Call(ctx context.Context, req *Request) (*Response, error) {
-	if deadline, ok := ctx.Deadline(); ok {
-		if err := c.conn.SetDeadline(deadline); err != nil {
-			return nil, fmt.Errorf("failed to set connection deadline: %w", err)
-		}
+func (c *TCPClient) Call(ctx context.Context, req *Request) (resp *Response, err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(resp, err) }()
+
+	if req.IsNotification() {
+		return nil, ErrMissingID
 	}
 
 	reqData, err := json.Marshal(req)
@@ -35,108 +107,186 @@ func (c *TCPClient) Call(ctx context.Context, req *Request) (*Response, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	if _, err := c.conn.Write(append(reqData, '\n')); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	key, err := idKey(req.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	scanner := bufio.NewScanner(c.conn)
-	if !scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
-		return nil, fmt.Errorf("connection closed without response")
+	respData, err := c.pool.call(ctx, reqData, []string{key})
+	if err != nil {
+		return nil, err
 	}
 
-	var rpcResp Response
-	if err := json.Unmarshal(scanner.Bytes(), &rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	return decodeResponse(respData, c.decoding)
+}
 
-	return &rpcResp, nil
+// Go sends a JSON-RPC request over TCP without waiting for the response, see [Call].
+// Calls are pipelined over the connections of the client, which are not held while waiting for responses.
+func (c *TCPClient) Go(ctx context.Context, req *Request) *Call {
+	return Go(ctx, req, c.Call)
 }
 
 // CallBatch sends a batch of JSON-RPC requests over TCP and returns the responses.
+// A batch made only of notifications is sent without waiting for a reply, and nil is returned.
 func (c *TCPClient) CallBatch(ctx context.Context, reqs []*Request) (any, error) {
-	if deadline, ok := ctx.Deadline(); ok {
-		if err := c.conn.SetDeadline(deadline); err != nil {
-			return nil, fmt.Errorf("failed to set connection deadline: %w", err)
-		}
+	respData, err := c.sendBatch(ctx, reqs)
+	if err != nil || respData == nil {
+		return nil, err
 	}
 
-	reqData, err := json.Marshal(reqs)
+	rpcResp, err := decodeBatch(respData, c.decoding)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
 	}
 
-	if _, err := c.conn.Write(append(reqData, '\n')); err != nil {
-		return nil, fmt.Errorf("failed to send batch request: %w", err)
+	return rpcResp, nil
+}
+
+// CallBatchResponses implements [BatchCaller].
+func (c *TCPClient) CallBatchResponses(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	respData, err := c.sendBatch(ctx, reqs)
+	if err != nil || respData == nil {
+		return nil, err
 	}
+	return decodeResponses(respData, c.decoding)
+}
 
-	scanner := bufio.NewScanner(c.conn)
-	if !scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("failed to read batch response: %w", err)
-		}
-		return nil, fmt.Errorf("connection closed without response")
+// sendBatch sends a batch of JSON-RPC requests over TCP and returns the reply undecoded, or nil if no reply is expected.
+func (c *TCPClient) sendBatch(ctx context.Context, reqs []*Request) (reply []byte, err error) {
+	done := startBatch(c.metrics, reqs)
+	defer func() { done(reply, err) }()
+
+	keys, err := batchKeys(reqs)
+	if err != nil {
+		return nil, err
 	}
 
-	var rpcResp any
-	if err := json.Unmarshal(scanner.Bytes(), &rpcResp); err != nil {
-		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	reqData, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
 	}
 
-	return rpcResp, nil
+	if len(keys) == 0 {
+		return nil, c.pool.notify(ctx, reqData)
+	}
+	return c.pool.call(ctx, reqData, keys)
 }
 
-// Notify sends a JSON-RPC notification over TCP.
-func (c *TCPClient) Notify(ctx context.Context, req *Request) error {
-	if deadline, ok := ctx.Deadline(); ok {
-		if err := c.conn.SetDeadline(deadline); err != nil {
-			return fmt.Errorf("failed to set connection deadline: %w", err)
-		}
-	}
+// Notify sends a JSON-RPC notification over TCP. The ID of req, if any, is not sent.
+func (c *TCPClient) Notify(ctx context.Context, req *Request) (err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(nil, err) }()
 
-	reqData, err := json.Marshal(req)
+	reqData, err := json.Marshal(req.asNotification())
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	if _, err := c.conn.Write(append(reqData, '\n')); err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
+	return c.pool.notify(ctx, reqData)
+}
 
-	return nil
+// Stats returns the current health of the client's connections.
+func (c *TCPClient) Stats() PoolStats {
+	return c.pool.stats()
 }
 
-// TCPServer is a JSON-RPC 2.0 server that handles TCP connections.
-type TCPServer struct {
-	handlers map[string]Handler
-	addr     string
+// Close closes all connections of the client.
+// Calls made after Close fail with [ErrClientClosed].
+func (c *TCPClient) Close() error {
+	return c.pool.close()
 }
 
+// StreamServer is a JSON-RPC 2.0 server that exchanges newline-delimited messages over stream connections,
+// such as TCP connections or Unix domain sockets.
+// Messages of a connection are handled one at a time unless [WithConnectionHandlers] allows more.
+type StreamServer struct {
+	*dispatcher
+	network      string
+	addr         string
+	listener     net.Listener
+	socketMode   os.FileMode
+	tlsConfig    *tls.Config
+	connHandlers int
+
+	handshakeTimeout time.Duration
+}
+
+// TCPServer is a [StreamServer] that handles TCP connections.
+type TCPServer = StreamServer
+
 // NewTCPServer creates a new [TCPServer] with an empty handlers.
-func NewTCPServer(addr string) *TCPServer {
-	return &TCPServer{
-		handlers: make(map[string]Handler),
-		addr:     addr,
+// Use [WithNetwork] or [WithListener] to serve other kinds of stream connections, and [WithTLSConfig] to require TLS.
+func NewTCPServer(addr string, opts ...ServerOption) *TCPServer {
+	o := newServerOptions(opts)
+	network := o.network
+	if network == "" {
+		network = "tcp"
+	}
+	return &StreamServer{
+		dispatcher:   newDispatcher(o),
+		network:      network,
+		addr:         addr,
+		listener:     o.listener,
+		socketMode:   o.socketMode,
+		tlsConfig:    o.tlsConfig,
+		connHandlers: max(o.connHandlers, 1),
+
+		handshakeTimeout: o.handshakeTimeout,
 	}
 }
 
-var _ Server = (*TCPServer)(nil)
+// WithNetwork sets the network a [StreamServer] listens on, as accepted by [net.Listen], e.g. "tcp4" or "unix".
+func WithNetwork(network string) ServerOption {
+	return func(o *serverOptions) {
+		o.network = network
+	}
+}
+
+// WithListener makes a [StreamServer] serve connections accepted by listener instead of listening itself.
+// The listener is closed when the server stops.
+func WithListener(listener net.Listener) ServerOption {
+	return func(o *serverOptions) {
+		o.listener = listener
+	}
+}
+
+var _ Describer = (*StreamServer)(nil)
 
 // Register registers a handler for a specific method.
-func (s *TCPServer) Register(method string, handler Handler) {
-	s.handlers[method] = handler
+func (s *StreamServer) Register(method string, handler Handler) {
+	s.dispatcher.Register(method, handler)
 }
 
-// Run starts the TCP server and listens for incoming connections.
-func (s *TCPServer) Run(ctx context.Context) error {
-	listener, err := net.Listen("tcp", s.addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+// RegisterMethod registers a handler for a specific method.
+// The options describe the method in the document served by rpc.discover, see [RegisterOption].
+func (s *StreamServer) RegisterMethod(method string, handler Handler, opts ...RegisterOption) {
+	s.dispatcher.RegisterMethod(method, handler, opts...)
+}
+
+// Run starts the server and listens for incoming connections.
+func (s *StreamServer) Run(ctx context.Context) error {
+	listener := s.listener
+	if listener == nil {
+		var err error
+		listener, err = s.listen()
+		if err != nil {
+			return err
+		}
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve accepts connections from listener and serves them until ctx is done.
+// The listener and all of its connections are closed when Serve returns.
+// If a TLS configuration is set with [WithTLSConfig], TLS is negotiated on the accepted connections.
+func (s *StreamServer) Serve(ctx context.Context, listener net.Listener) error {
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
 	}
 	defer listener.Close()
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	go func() {
 		<-ctx.Done()
 		listener.Close()
@@ -149,46 +299,114 @@ func (s *TCPServer) Run(ctx context.Context) error {
 			case <-ctx.Done():
 				return ctx.Err()
 			default:
-				continue
 			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
 		}
-		go s.handleConnection(ctx, conn)
+		if !s.limiter.acquireConn() {
+			go rejectConnection(conn)
+			continue
+		}
+		go func() {
+			defer s.limiter.releaseConn()
+			s.handleConnection(ctx, conn)
+		}()
+	}
+}
+
+// listen creates the listener for the configured network and address.
+func (s *StreamServer) listen() (net.Listener, error) {
+	if isUnixNetwork(s.network) {
+		return listenUnix(s.network, s.addr, s.socketMode)
+	}
+	listener, err := net.Listen(s.network, s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.addr, err)
 	}
+	return listener, nil
 }
 
-// handleConnection handles a single TCP connection.
-func (s *TCPServer) handleConnection(ctx context.Context, conn net.Conn) {
+// rejectConnection answers conn with a [LimitExceeded] error and closes it.
+func rejectConnection(conn net.Conn) {
 	defer conn.Close()
-	scanner := bufio.NewScanner(conn)
-	encoder := json.NewEncoder(conn)
+	conn.SetDeadline(time.Now().Add(time.Second))
+	if err := handshake(context.Background(), conn, time.Second); err != nil {
+		return
+	}
+	json.NewEncoder(conn).Encode(limitExceeded(nil, "connections", 0))
+}
 
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+// ServeConn serves a single connection, e.g. one end of a [net.Pipe], until it is closed or ctx is done.
+// The connection counts towards the limit set by [WithMaxConnections] and is closed when ServeConn returns.
+func (s *StreamServer) ServeConn(ctx context.Context, conn net.Conn) {
+	if s.tlsConfig != nil {
+		conn = tls.Server(conn, s.tlsConfig)
+	}
+	if !s.limiter.acquireConn() {
+		rejectConnection(conn)
+		return
+	}
+	defer s.limiter.releaseConn()
+	s.handleConnection(ctx, conn)
+}
 
+// handleConnection handles a single connection.
+func (s *StreamServer) handleConnection(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := handshake(ctx, conn, s.handshakeTimeout); err != nil {
+		return
+	}
+	peer := newPeer(conn)
+	ctx = contextWithPeer(ctx, peer)
+	ctx, _ = s.startSession(ctx, &Credentials{Peer: peer})
+	scanner := bufio.NewScanner(conn)
+	s.setScannerLimit(scanner)
+	tap := s.tap.openConn(TapServer, conn)
+	defer openConn(s.metrics, conn.LocalAddr().Network())()
+
+	var (
+		wg      sync.WaitGroup
+		writeMu sync.Mutex
+	)
+	defer wg.Wait()
+	write := func(reply any) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		writeLine(conn, tap, reply)
+	}
+	sem := make(chan struct{}, s.connHandlers)
+	for scanner.Scan() {
 		line := scanner.Bytes()
-		if len(line) == 0 {
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		var req Request
-		if err := json.Unmarshal(line, &req); err != nil {
-			errorResp := NewError(ParseError, "Parse error")
-			resp := NewResponse(nil, WithError(*errorResp))
-			encoder.Encode(resp)
-			continue
-		}
+		tap.record(TapReceive, line)
 
-		if handler, exists := s.handlers[req.Method]; exists {
-			resp := handler(ctx, &req)
-			encoder.Encode(resp)
-		} else {
-			err := NewError(MethodNotFound, "Method not found")
-			resp := NewResponse(req.ID, WithError(*err))
-			encoder.Encode(resp)
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
 		}
+		// The scanner reuses its buffer for the next line.
+		data := bytes.Clone(line)
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if reply := s.handleMessage(ctx, data); reply != nil {
+				write(reply)
+			}
+		}()
+	}
+	if resp := scanError(scanner.Err()); resp != nil {
+		write(resp)
 	}
 }
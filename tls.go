@@ -0,0 +1,68 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// defaultHandshakeTimeout is how long a [StreamServer] waits for the TLS handshake of a connection, see [WithHandshakeTimeout].
+const defaultHandshakeTimeout = 10 * time.Second
+
+// WithTLSConfig makes a [StreamServer] accept TLS connections configured by cfg, which must contain at least one certificate.
+// For mutual TLS, set ClientAuth to [tls.RequireAndVerifyClientCert] and ClientCAs to the accepted authorities;
+// handlers can then inspect the verified client certificate chains through the TLS field of [PeerFromContext].
+func WithTLSConfig(cfg *tls.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithHandshakeTimeout sets how long a [StreamServer] configured with [WithTLSConfig] waits for clients to complete
+// the TLS handshake of their connections before closing them. It defaults to 10 seconds.
+func WithHandshakeTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.handshakeTimeout = timeout
+	}
+}
+
+// TLSDialer returns a [Dialer] that connects to addr over TCP and performs a TLS handshake configured by cfg.
+// If the ServerName of cfg is empty, the host of addr is verified.
+// For mutual TLS, set the Certificates or GetClientCertificate of cfg.
+func TLSDialer(addr string, cfg *tls.Config) Dialer {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			cfg.ServerName = host
+		} else {
+			cfg.ServerName = addr
+		}
+	}
+	return func(ctx context.Context) (net.Conn, error) {
+		d := tls.Dialer{Config: cfg}
+		return d.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// handshake completes the TLS handshake of conn, if it is a TLS connection, so that its state is known before any request is handled.
+// It gives up after timeout, or [defaultHandshakeTimeout] if timeout is not positive.
+func handshake(ctx context.Context, conn net.Conn, timeout time.Duration) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("failed to complete TLS handshake: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package jsonrpc2_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mi-wada/go-jsonrpc2"
+)
+
+// testPKI is a certificate authority with a server certificate for 127.0.0.1 and a client certificate it issued.
+type testPKI struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return &testPKI{
+		pool:   pool,
+		server: issue(2, "server", x509.ExtKeyUsageServerAuth),
+		client: issue(3, "client", x509.ExtKeyUsageClientAuth),
+	}
+}
+
+// peerInfo is the result of the method "peer", describing the connection of the caller.
+type peerInfo struct {
+	TLS    bool   `json:"tls"`
+	Client string `json:"client"` // The common name of the verified client certificate, if any.
+}
+
+// serveTLS serves a stream server with the method "peer" on a local TCP port until the test ends, and returns its address.
+func serveTLS(t *testing.T, opts ...jsonrpc2.ServerOption) string {
+	t.Helper()
+	s := jsonrpc2.NewTCPServer("", opts...)
+	s.Register("peer", func(ctx context.Context, req *jsonrpc2.Request) *jsonrpc2.Response {
+		var info peerInfo
+		peer, ok := jsonrpc2.PeerFromContext(ctx)
+		if ok && peer.TLS != nil {
+			info.TLS = true
+			if chains := peer.TLS.VerifiedChains; len(chains) > 0 {
+				info.Client = chains[0][0].Subject.CommonName
+			}
+		}
+		return jsonrpc2.NewResponse(req.ID, jsonrpc2.WithResult(info))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Serve(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln.Addr().String()
+}
+
+func TestTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{pki.server}}
+	mutualTLS := &tls.Config{
+		Certificates: []tls.Certificate{pki.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.pool,
+	}
+	tests := []struct {
+		name    string
+		server  *tls.Config
+		client  *tls.Config // Nil to connect without TLS.
+		want    peerInfo
+		wantErr bool
+	}{
+		{
+			name:   "TLS",
+			server: serverTLS,
+			client: &tls.Config{RootCAs: pki.pool},
+			want:   peerInfo{TLS: true},
+		},
+		{
+			name:    "untrusted server",
+			server:  serverTLS,
+			client:  &tls.Config{},
+			wantErr: true,
+		},
+		{
+			name:    "plaintext client",
+			server:  serverTLS,
+			wantErr: true,
+		},
+		{
+			name:   "mutual TLS",
+			server: mutualTLS,
+			client: &tls.Config{RootCAs: pki.pool, Certificates: []tls.Certificate{pki.client}},
+			want:   peerInfo{TLS: true, Client: "client"},
+		},
+		{
+			name:    "mutual TLS without client certificate",
+			server:  mutualTLS,
+			client:  &tls.Config{RootCAs: pki.pool},
+			wantErr: true,
+		},
+		{
+			name:   "client certificate not requested",
+			server: serverTLS,
+			client: &tls.Config{RootCAs: pki.pool, Certificates: []tls.Certificate{pki.client}},
+			want:   peerInfo{TLS: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serveTLS(t, jsonrpc2.WithTLSConfig(tt.server))
+			dialer := jsonrpc2.TCPDialer(addr)
+			if tt.client != nil {
+				dialer = jsonrpc2.TLSDialer(addr, tt.client)
+			}
+			client := jsonrpc2.NewTCPClientWithDialer(dialer)
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := jsonrpc2.NewRequest("peer", jsonrpc2.WithID(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Call(ctx, req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got response %+v, want error", resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+			if resp.Error != nil {
+				t.Fatalf("got error %v, want result", resp.Error)
+			}
+			result, err := json.Marshal(resp.Result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got peerInfo
+			if err := json.Unmarshal(result, &got); err != nil {
+				t.Fatalf("failed to decode result: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got peer %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := serveTLS(t,
+		jsonrpc2.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{pki.server}}),
+		jsonrpc2.WithHandshakeTimeout(50*time.Millisecond),
+	)
+
+	// A client that never starts the handshake is disconnected.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatal("read from connection succeeded, want it closed")
+	}
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Errorf("connection closed after %v, want after the handshake timeout", elapsed)
+	}
+}
@@ -0,0 +1,134 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// NewUnixServer creates a new [StreamServer] that listens on the Unix domain socket at path.
+// A stale socket file left at path by a previous run is removed on start,
+// and the socket file is removed when the server stops.
+// Use [WithSocketMode] to restrict who may connect, and [PeerFromContext] in handlers
+// to inspect the credentials of the connected process.
+func NewUnixServer(path string, opts ...ServerOption) *StreamServer {
+	return NewTCPServer(path, append([]ServerOption{WithNetwork("unix")}, opts...)...)
+}
+
+// WithSocketMode sets the file mode of the socket file created by a [StreamServer] listening on a Unix domain socket,
+// e.g. 0o600 to only accept connections from processes of the same user.
+func WithSocketMode(mode os.FileMode) ServerOption {
+	return func(o *serverOptions) {
+		o.socketMode = mode
+	}
+}
+
+// UnixDialer returns a [Dialer] that connects to the Unix domain socket at path.
+func UnixDialer(path string) Dialer {
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
+// isUnixNetwork reports whether network denotes a Unix domain socket.
+func isUnixNetwork(network string) bool {
+	return network == "unix" || network == "unixpacket"
+}
+
+// listenUnix listens on the Unix domain socket at path, replacing a stale socket file and applying mode.
+func listenUnix(network, path string, mode os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	if mode != 0 && !isAbstractSocket(path) {
+		return listenUnixMode(network, path, mode)
+	}
+
+	listener, err := net.Listen(network, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if unixListener, ok := listener.(*net.UnixListener); ok {
+		unixListener.SetUnlinkOnClose(true)
+	}
+	return listener, nil
+}
+
+// listenUnixMode listens on the Unix domain socket at path with the file mode mode. The socket is created in a private
+// directory next to path and moved to path once its mode is set, so that no process can connect before.
+func listenUnixMode(network, path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".jsonrpc2-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "s")
+	listener, err := net.Listen(network, tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if unixListener, ok := listener.(*net.UnixListener); ok {
+		unixListener.SetUnlinkOnClose(false)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to move socket to %s: %w", path, err)
+	}
+	return &unlinkListener{Listener: listener, path: path}, nil
+}
+
+// unlinkListener is a [net.Listener] removing the socket file at path when closed.
+type unlinkListener struct {
+	net.Listener
+	path string
+	once sync.Once
+}
+
+func (l *unlinkListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { os.Remove(l.path) })
+	return err
+}
+
+// removeStaleSocket removes the socket file at path if no server is listening on it.
+func removeStaleSocket(path string) error {
+	if isAbstractSocket(path) {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// isAbstractSocket reports whether path names a socket in the Linux abstract namespace, which has no file.
+func isAbstractSocket(path string) bool {
+	return strings.HasPrefix(path, "@")
+}
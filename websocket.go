@@ -0,0 +1,348 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocketClient is a JSON-RPC 2.0 client that communicates over a persistent WebSocket connection.
+// It is safe for concurrent use; concurrent calls share the connection and are matched to their responses by ID.
+type WebSocketClient struct {
+	mux      *clientMux
+	decoding resultDecoding
+	metrics  Metrics
+}
+
+// DialWebSocket connects to the ws:// or wss:// URL and creates a new [WebSocketClient].
+// The headers and credentials set with [WithHTTPHeader], [WithHTTPAuth] and [WithUserAgent] are sent with the opening handshake.
+// Use [WithNotificationHandler] to receive notifications pushed by the server and [WithPingInterval] to keep the connection alive.
+func DialWebSocket(ctx context.Context, url string, opts ...ClientOption) (*WebSocketClient, error) {
+	o := newClientOptions(opts)
+	conn, err := dialWebSocket(ctx, url, o.tlsConfig, func(req *http.Request) error {
+		for k, v := range o.httpHeader {
+			req.Header[k] = v
+		}
+		if o.userAgent != "" {
+			req.Header.Set("User-Agent", o.userAgent)
+		}
+		if o.httpAuth != nil {
+			return o.httpAuth(ctx, req)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn.maxMessageSize = o.maxMessageSize
+
+	c := &WebSocketClient{
+		mux:      newClientMux(tapMessages(conn, o.tap.open(TapClient, "websocket", url)), o.notificationHandler),
+		decoding: o.resultDecoding,
+		metrics:  o.metrics,
+	}
+	c.mux.reportConn(o.metrics, "websocket")
+	if o.pingInterval > 0 {
+		go conn.keepAlive(o.pingInterval, c.mux.done)
+	}
+	return c, nil
+}
+
+// WithPingInterval makes a [WebSocketClient] ping the server every interval.
+// The connection is closed if no pong is received for two intervals.
+func WithPingInterval(interval time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.pingInterval = interval
+	}
+}
+
+// WithTLSClientConfig sets the TLS configuration a [WebSocketClient] uses to connect to wss:// URLs.
+// If its ServerName is empty, the host of the URL is verified.
+func WithTLSClientConfig(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.tlsConfig = cfg
+	}
+}
+
+var _ Client = (*WebSocketClient)(nil)
+
+var _ BatchCaller = (*WebSocketClient)(nil)
+
+// Call sends a JSON-RPC 2.0 request over the WebSocket connection and waits for its response.
+func (c *WebSocketClient) Call(ctx context.Context, req *Request) (resp *Response, err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(resp, err) }()
+
+	if req.IsNotification() {
+		return nil, ErrMissingID
+	}
+
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	key, err := idKey(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := c.mux.call(ctx, reqData, []string{key})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResponse(respData, c.decoding)
+}
+
+// Go sends a JSON-RPC request over the WebSocket connection without waiting for the response, see [Call].
+func (c *WebSocketClient) Go(ctx context.Context, req *Request) *Call {
+	return Go(ctx, req, c.Call)
+}
+
+// CallBatch sends a batch of JSON-RPC requests over the WebSocket connection and waits for the responses.
+// A batch made only of notifications is sent without waiting for a reply, and nil is returned.
+func (c *WebSocketClient) CallBatch(ctx context.Context, reqs []*Request) (any, error) {
+	respData, err := c.sendBatch(ctx, reqs)
+	if err != nil || respData == nil {
+		return nil, err
+	}
+
+	rpcResp, err := decodeBatch(respData, c.decoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	}
+	return rpcResp, nil
+}
+
+// CallBatchResponses implements [BatchCaller].
+func (c *WebSocketClient) CallBatchResponses(ctx context.Context, reqs []*Request) ([]*Response, error) {
+	respData, err := c.sendBatch(ctx, reqs)
+	if err != nil || respData == nil {
+		return nil, err
+	}
+	return decodeResponses(respData, c.decoding)
+}
+
+// sendBatch sends a batch of JSON-RPC requests over the WebSocket connection and returns the reply undecoded,
+// or nil if no reply is expected.
+func (c *WebSocketClient) sendBatch(ctx context.Context, reqs []*Request) (reply []byte, err error) {
+	done := startBatch(c.metrics, reqs)
+	defer func() { done(reply, err) }()
+
+	keys, err := batchKeys(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	reqData, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, c.mux.notify(ctx, reqData)
+	}
+	return c.mux.call(ctx, reqData, keys)
+}
+
+// Notify sends a JSON-RPC notification over the WebSocket connection. The ID of req, if any, is not sent.
+func (c *WebSocketClient) Notify(ctx context.Context, req *Request) (err error) {
+	done := startRequest(c.metrics, req.Method)
+	defer func() { done(nil, err) }()
+
+	reqData, err := json.Marshal(req.asNotification())
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return c.mux.notify(ctx, reqData)
+}
+
+// Close closes the WebSocket connection.
+// Calls in flight and made after Close fail with [ErrClientClosed].
+func (c *WebSocketClient) Close() error {
+	return c.mux.close()
+}
+
+// WebSocketServer is a JSON-RPC 2.0 server that handles persistent WebSocket connections.
+// Messages of a connection are handled one at a time unless [WithConnectionHandlers] allows more, and handlers can push notifications
+// to the client through the [Notifier] returned by [NotifierFromContext].
+// It implements [http.Handler], so it can also be mounted on an existing HTTP server.
+type WebSocketServer struct {
+	*dispatcher
+	addr         string
+	path         string
+	pingInterval time.Duration
+	checkOrigin  func(r *http.Request) bool
+	connHandlers int
+}
+
+// NewWebSocketServer creates a new [WebSocketServer] with an empty handlers.
+// addr and path are only used by [WebSocketServer.Run].
+func NewWebSocketServer(addr, path string, opts ...ServerOption) *WebSocketServer {
+	o := newServerOptions(opts)
+	checkOrigin := o.checkOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	return &WebSocketServer{
+		dispatcher:   newDispatcher(o),
+		addr:         addr,
+		path:         path,
+		pingInterval: o.pingInterval,
+		checkOrigin:  checkOrigin,
+		connHandlers: max(o.connHandlers, 1),
+	}
+}
+
+// WithServerPingInterval makes a [WebSocketServer] ping its clients every interval.
+// Connections are closed if no pong is received for two intervals.
+func WithServerPingInterval(interval time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.pingInterval = interval
+	}
+}
+
+// WithOriginCheck sets the function a [WebSocketServer] uses to accept the Origin of opening handshakes.
+// By default, only requests without Origin or with an Origin matching the Host header are accepted.
+func WithOriginCheck(check func(r *http.Request) bool) ServerOption {
+	return func(o *serverOptions) {
+		o.checkOrigin = check
+	}
+}
+
+var _ Describer = (*WebSocketServer)(nil)
+
+var _ http.Handler = (*WebSocketServer)(nil)
+
+// Register registers a handler for a specific method.
+func (s *WebSocketServer) Register(method string, handler Handler) {
+	s.dispatcher.Register(method, handler)
+}
+
+// RegisterMethod registers a handler for a specific method.
+// The options describe the method in the document served by rpc.discover, see [RegisterOption].
+func (s *WebSocketServer) RegisterMethod(method string, handler Handler, opts ...RegisterOption) {
+	s.dispatcher.RegisterMethod(method, handler, opts...)
+}
+
+// Run starts an HTTP server that accepts WebSocket connections on the configured path.
+// All connections are closed when ctx is done.
+func (s *WebSocketServer) Run(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.path, s)
+	server := &http.Server{
+		Addr:        s.addr,
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	return server.ListenAndServe()
+}
+
+// Path returns the path [WebSocketServer.Run] accepts WebSocket connections on.
+func (s *WebSocketServer) Path() string {
+	return s.path
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it until it is closed.
+func (s *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.checkOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	if !s.limiter.acquireConn() {
+		http.Error(w, "Too many connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.limiter.releaseConn()
+
+	peer := requestPeer(r)
+	ctx := context.WithValue(r.Context(), requestHeaderKey{}, r.Header)
+	ctx, err := s.startSession(ctx, &Credentials{Header: r.Header, Peer: peer})
+	if err != nil && s.loginMethod == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	if s.maxMessageSize > 0 {
+		conn.maxMessageSize = s.maxMessageSize
+	}
+	s.serveConn(ctx, conn)
+}
+
+// serveConn reads and handles messages of conn until it fails or ctx is done.
+func (s *WebSocketServer) serveConn(ctx context.Context, conn *wsConn) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if s.pingInterval > 0 {
+		go conn.keepAlive(s.pingInterval, ctx.Done())
+	}
+
+	peer := newPeer(conn.conn)
+	ctx = contextWithPeer(ctx, peer)
+	messages := tapMessages(conn, s.tap.open(TapServer, "websocket", peer.RemoteAddr))
+	ctx = contextWithNotifier(ctx, messageNotifier{messages})
+	defer openConn(s.metrics, "websocket")()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.connHandlers)
+read:
+	for {
+		data, err := messages.readMessage()
+		if err != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break read
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			reply := s.handleMessage(ctx, data)
+			if reply == nil {
+				return
+			}
+			replyData, _ := marshalReply(reply)
+			messages.writeMessage(ctx, replyData)
+		}()
+	}
+
+	wg.Wait()
+	cancel()
+}
+
+// sameOrigin reports whether the request has no Origin header or one whose host matches the Host header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
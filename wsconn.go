@@ -0,0 +1,474 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// This file implements the subset of the WebSocket protocol (RFC 6455) needed to carry JSON-RPC messages:
+// the opening handshake, fragmented text and binary messages, ping/pong and the closing handshake.
+// Extensions such as compression are not supported.
+
+const (
+	websocketGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	websocketVersion = "13"
+
+	// defaultMaxWebSocketMessageSize is the maximum size of a received WebSocket message.
+	defaultMaxWebSocketMessageSize = 32 << 20
+
+	// wsControlTimeout is how long writing a pong or close frame may take.
+	wsControlTimeout = time.Second
+)
+
+// WebSocket frame opcodes.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WebSocket close status codes.
+const (
+	wsCloseNormal          = 1000
+	wsCloseProtocolError   = 1002
+	wsCloseNoStatus        = 1005
+	wsCloseAbnormal        = 1006
+	wsCloseInvalidData     = 1007
+	wsCloseMessageTooLarge = 1009
+	wsCloseTLSHandshake    = 1015
+)
+
+// errWebSocketProtocol is returned when the peer violates the WebSocket protocol.
+var errWebSocketProtocol = errors.New("websocket protocol error")
+
+// wsConn is a WebSocket connection.
+type wsConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	client         bool // Whether this is the client side, which must mask the frames it sends.
+	maxMessageSize int64
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	lastPong  atomic.Int64 // The Unix nanoseconds of the last received pong.
+}
+
+// newWSConn creates a new [wsConn] over an established connection.
+func newWSConn(conn net.Conn, reader *bufio.Reader, client bool) *wsConn {
+	c := &wsConn{
+		conn:           conn,
+		reader:         reader,
+		client:         client,
+		maxMessageSize: defaultMaxWebSocketMessageSize,
+	}
+	c.lastPong.Store(time.Now().UnixNano())
+	return c
+}
+
+// readMessage reads the next text or binary message, answering control frames on the way.
+// Text messages that are not valid UTF-8 close the connection with status 1007.
+// It returns io.EOF once the peer closed the connection.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var (
+		msg     []byte
+		started bool
+		text    bool
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errWebSocketProtocol) {
+				c.closeWithStatus(wsCloseProtocolError)
+			}
+			return nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			ctx, cancel := context.WithTimeout(context.Background(), wsControlTimeout)
+			err := c.writeFrame(ctx, wsOpPong, payload)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			c.lastPong.Store(time.Now().UnixNano())
+			continue
+		case wsOpClose:
+			status := wsCloseNormal
+			if len(payload) >= 2 {
+				status = int(binary.BigEndian.Uint16(payload))
+			}
+			c.closeWithStatus(status)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary:
+			if started {
+				c.closeWithStatus(wsCloseProtocolError)
+				return nil, fmt.Errorf("%w: unexpected data frame within a fragmented message", errWebSocketProtocol)
+			}
+			started = true
+			text = op == wsOpText
+			msg = payload
+		case wsOpContinuation:
+			if !started {
+				c.closeWithStatus(wsCloseProtocolError)
+				return nil, fmt.Errorf("%w: unexpected continuation frame", errWebSocketProtocol)
+			}
+			msg = append(msg, payload...)
+		default:
+			c.closeWithStatus(wsCloseProtocolError)
+			return nil, fmt.Errorf("%w: unknown opcode %d", errWebSocketProtocol, op)
+		}
+
+		if int64(len(msg)) > c.maxMessageSize {
+			c.closeWithStatus(wsCloseMessageTooLarge)
+			return nil, fmt.Errorf("websocket message exceeds %d bytes", c.maxMessageSize)
+		}
+		if fin {
+			if text && !utf8.Valid(msg) {
+				c.closeWithStatus(wsCloseInvalidData)
+				return nil, fmt.Errorf("%w: invalid UTF-8 in text message", errWebSocketProtocol)
+			}
+			return msg, nil
+		}
+	}
+}
+
+// readFrame reads a single frame and unmasks its payload.
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	op = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", errWebSocketProtocol)
+	}
+	if masked == c.client {
+		return false, 0, nil, fmt.Errorf("%w: invalid frame masking", errWebSocketProtocol)
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, fmt.Errorf("%w: invalid control frame", errWebSocketProtocol)
+	}
+	if length > uint64(c.maxMessageSize) {
+		c.closeWithStatus(wsCloseMessageTooLarge)
+		return false, 0, nil, fmt.Errorf("websocket frame exceeds %d bytes", c.maxMessageSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+	return fin, op, payload, nil
+}
+
+// writeMessage sends data as a single text frame.
+func (c *wsConn) writeMessage(ctx context.Context, data []byte) error {
+	return c.writeFrame(ctx, wsOpText, data)
+}
+
+// writeFrame sends a single unfragmented frame. Frames sent by clients are masked.
+func (c *wsConn) writeFrame(ctx context.Context, op byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|op)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return fmt.Errorf("failed to generate mask: %w", err)
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// ping sends a ping frame.
+func (c *wsConn) ping(ctx context.Context) error {
+	return c.writeFrame(ctx, wsOpPing, nil)
+}
+
+// keepAlive pings the peer every interval until done is closed,
+// closing the connection if no pong arrived for two intervals.
+func (c *wsConn) keepAlive(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastPong.Load())) > 2*interval {
+				c.conn.Close()
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := c.ping(ctx)
+			cancel()
+			if err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// Close sends a normal close frame and closes the connection.
+func (c *wsConn) Close() error {
+	return c.closeWithStatus(wsCloseNormal)
+}
+
+// closeWithStatus sends a close frame with status and closes the connection.
+// The statuses reserved for reporting closures locally, which must not be sent, are replaced by 1000.
+func (c *wsConn) closeWithStatus(status int) error {
+	switch status {
+	case wsCloseNoStatus, wsCloseAbnormal, wsCloseTLSHandshake:
+		status = wsCloseNormal
+	}
+	var err error
+	c.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), wsControlTimeout)
+		defer cancel()
+		c.writeFrame(ctx, wsOpClose, binary.BigEndian.AppendUint16(nil, uint16(status)))
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// maskBytes applies the WebSocket masking algorithm to b in place.
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept value for key.
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContainsToken reports whether the comma-separated header name contains token, ignoring case.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeWebSocket performs the server side of the opening handshake.
+// On failure, it answers the HTTP request itself and returns an error.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, errors.New("websocket upgrade requires GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("missing websocket upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != websocketVersion {
+		w.Header().Set("Sec-WebSocket-Version", websocketVersion)
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+	return newWSConn(conn, rw.Reader, false), nil
+}
+
+// dialWebSocket performs the client side of the opening handshake with the ws:// or wss:// URL rawURL.
+// prepare may add headers to the handshake request.
+func dialWebSocket(ctx context.Context, rawURL string, tlsConfig *tls.Config, prepare func(*http.Request) error) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	var port string
+	switch u.Scheme {
+	case "ws":
+		u.Scheme, port = "http", "80"
+	case "wss":
+		u.Scheme, port = "https", "443"
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	if u.Scheme == "https" {
+		cfg := tlsConfig.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	ws, err := websocketHandshake(ctx, conn, u, prepare)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// websocketHandshake sends the opening handshake request over conn and validates the response.
+func websocketHandshake(ctx context.Context, conn net.Conn, u *url.URL, prepare func(*http.Request) error) (*wsConn, error) {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create handshake request: %w", err)
+	}
+	if prepare != nil {
+		if err := prepare(req); err != nil {
+			return nil, err
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", websocketVersion)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send handshake: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read handshake response: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp)
+	}
+	if !headerContainsToken(resp.Header, "Upgrade", "websocket") || resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		return nil, fmt.Errorf("%w: invalid handshake response", errWebSocketProtocol)
+	}
+	return newWSConn(conn, reader, true), nil
+}
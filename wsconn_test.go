@@ -0,0 +1,468 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveWebSocket serves a [WebSocketServer] echoing the params of the method "echo" until the test ends,
+// and returns its URL.
+func serveWebSocket(t *testing.T, opts ...ServerOption) string {
+	t.Helper()
+	s := NewWebSocketServer("", "/", opts...)
+	s.Register("echo", func(ctx context.Context, req *Request) *Response {
+		return NewResponse(req.ID, WithResult(req.Params))
+	})
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// dialTestWebSocket opens a raw WebSocket connection to url.
+func dialTestWebSocket(t *testing.T, url string) *wsConn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialWebSocket(ctx, url, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.conn.Close() })
+	conn.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// writeRawFrame sends a frame with the given FIN bit, opcode and payload, masked if mask is set,
+// so that tests can send the fragments and invalid frames [wsConn.writeFrame] does not produce.
+func writeRawFrame(t *testing.T, c *wsConn, fin bool, op byte, payload []byte, mask bool) {
+	t.Helper()
+	first := op
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	payload = bytes.Clone(payload)
+	if mask {
+		var key [4]byte
+		rand.Read(key[:])
+		maskBytes(key, payload)
+		frame = append(frame, key[:]...)
+	}
+	frame = append(frame, payload...)
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+}
+
+// echoRequest returns an echo request whose params are a string of n bytes.
+func echoRequest(t *testing.T, n int) []byte {
+	t.Helper()
+	req, err := NewRequest("echo", WithParams([]string{strings.Repeat("x", n)}), WithID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkEcho checks that reply answers the echo request of n bytes.
+func checkEcho(t *testing.T, reply []byte, n int) {
+	t.Helper()
+	var resp struct {
+		Result []string `json:"result"`
+		ID     int      `json:"id"`
+	}
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		t.Fatalf("invalid reply %.100s: %v", reply, err)
+	}
+	if resp.ID != 1 || len(resp.Result) != 1 || len(resp.Result[0]) != n {
+		t.Fatalf("got reply %.100s, want an echo of %d bytes", reply, n)
+	}
+}
+
+// closeStatus reads frames of c until a close frame and returns its status.
+func closeStatus(t *testing.T, c *wsConn) int {
+	t.Helper()
+	for {
+		_, op, payload, err := c.readFrame()
+		if err != nil {
+			t.Fatalf("failed to read close frame: %v", err)
+		}
+		if op == wsOpClose {
+			if len(payload) < 2 {
+				t.Fatalf("got close frame without status")
+			}
+			return int(binary.BigEndian.Uint16(payload))
+		}
+	}
+}
+
+func TestWebSocketFraming(t *testing.T) {
+	// The payload lengths are encoded in 7, 16 and 64 bits respectively.
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "short", size: 10},
+		{name: "16-bit length", size: 1000},
+		{name: "64-bit length", size: 70000},
+	}
+	url := serveWebSocket(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialTestWebSocket(t, url)
+			if err := conn.writeMessage(context.Background(), echoRequest(t, tt.size)); err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+			reply, err := conn.readMessage()
+			if err != nil {
+				t.Fatalf("failed to read: %v", err)
+			}
+			checkEcho(t, reply, tt.size)
+		})
+	}
+}
+
+func TestWebSocketFragmentation(t *testing.T) {
+	conn := dialTestWebSocket(t, serveWebSocket(t))
+	msg := echoRequest(t, 100)
+	third := len(msg) / 3
+
+	// Control frames may be interleaved with the fragments of a message.
+	writeRawFrame(t, conn, false, wsOpText, msg[:third], true)
+	writeRawFrame(t, conn, true, wsOpPing, []byte("between"), true)
+	writeRawFrame(t, conn, false, wsOpContinuation, msg[third:2*third], true)
+	writeRawFrame(t, conn, true, wsOpContinuation, msg[2*third:], true)
+
+	fin, op, payload, err := conn.readFrame()
+	if err != nil {
+		t.Fatalf("failed to read pong: %v", err)
+	}
+	if !fin || op != wsOpPong || string(payload) != "between" {
+		t.Fatalf("got frame (fin %v, op %d, %q), want pong %q", fin, op, payload, "between")
+	}
+	reply, err := conn.readMessage()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	checkEcho(t, reply, 100)
+}
+
+func TestWebSocketPing(t *testing.T) {
+	conn := dialTestWebSocket(t, serveWebSocket(t))
+	writeRawFrame(t, conn, true, wsOpPing, []byte("hello"), true)
+	fin, op, payload, err := conn.readFrame()
+	if err != nil {
+		t.Fatalf("failed to read pong: %v", err)
+	}
+	if !fin || op != wsOpPong || string(payload) != "hello" {
+		t.Errorf("got frame (fin %v, op %d, %q), want pong %q", fin, op, payload, "hello")
+	}
+}
+
+func TestWebSocketServerPing(t *testing.T) {
+	conn := dialTestWebSocket(t, serveWebSocket(t, WithServerPingInterval(20*time.Millisecond)))
+	_, op, _, err := conn.readFrame()
+	if err != nil {
+		t.Fatalf("failed to read ping: %v", err)
+	}
+	if op != wsOpPing {
+		t.Fatalf("got opcode %d, want ping", op)
+	}
+
+	// A client that does not answer pings is disconnected after two intervals.
+	for {
+		if _, _, _, err := conn.readFrame(); err != nil {
+			break
+		}
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ServerOption
+		send       func(t *testing.T, c *wsConn)
+		wantStatus int
+	}{
+		{
+			name: "closing handshake",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseNormal), true)
+			},
+			wantStatus: wsCloseNormal,
+		},
+		{
+			name: "closing handshake with status",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpClose, binary.BigEndian.AppendUint16(nil, 1001), true)
+			},
+			wantStatus: 1001,
+		},
+		{
+			name: "closing handshake with reserved status 1005",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseNoStatus), true)
+			},
+			wantStatus: wsCloseNormal,
+		},
+		{
+			name: "closing handshake with reserved status 1006",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseAbnormal), true)
+			},
+			wantStatus: wsCloseNormal,
+		},
+		{
+			name: "closing handshake with reserved status 1015",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseTLSHandshake), true)
+			},
+			wantStatus: wsCloseNormal,
+		},
+		{
+			name: "unmasked frame",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpText, echoRequest(t, 1), false)
+			},
+			wantStatus: wsCloseProtocolError,
+		},
+		{
+			name: "unexpected continuation",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpContinuation, []byte("{}"), true)
+			},
+			wantStatus: wsCloseProtocolError,
+		},
+		{
+			name: "data frame within fragmented message",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, false, wsOpText, []byte("{"), true)
+				writeRawFrame(t, c, true, wsOpText, []byte("}"), true)
+			},
+			wantStatus: wsCloseProtocolError,
+		},
+		{
+			name: "fragmented control frame",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, false, wsOpPing, nil, true)
+			},
+			wantStatus: wsCloseProtocolError,
+		},
+		{
+			name: "unknown opcode",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, 0x3, []byte("{}"), true)
+			},
+			wantStatus: wsCloseProtocolError,
+		},
+		{
+			name: "invalid UTF-8",
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpText, []byte("\"\xff\xfe\""), true)
+			},
+			wantStatus: wsCloseInvalidData,
+		},
+		{
+			name: "invalid UTF-8 across fragments",
+			send: func(t *testing.T, c *wsConn) {
+				// A valid two-byte sequence split across fragments, followed by a truncated one.
+				writeRawFrame(t, c, false, wsOpText, []byte("\"\xc3"), true)
+				writeRawFrame(t, c, true, wsOpContinuation, []byte("\xa9\xc3\""), true)
+			},
+			wantStatus: wsCloseInvalidData,
+		},
+		{
+			name: "message too large",
+			opts: []ServerOption{WithMaxMessageSize(100)},
+			send: func(t *testing.T, c *wsConn) {
+				writeRawFrame(t, c, true, wsOpText, echoRequest(t, 200), true)
+			},
+			wantStatus: wsCloseMessageTooLarge,
+		},
+		{
+			name: "fragmented message too large",
+			opts: []ServerOption{WithMaxMessageSize(100)},
+			send: func(t *testing.T, c *wsConn) {
+				msg := echoRequest(t, 200)
+				writeRawFrame(t, c, false, wsOpText, msg[:80], true)
+				writeRawFrame(t, c, true, wsOpContinuation, msg[80:], true)
+			},
+			wantStatus: wsCloseMessageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialTestWebSocket(t, serveWebSocket(t, tt.opts...))
+			tt.send(t, conn)
+			if got := closeStatus(t, conn); got != tt.wantStatus {
+				t.Errorf("got close status %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWebSocketClientClose(t *testing.T) {
+	url := serveWebSocket(t)
+	client, err := DialWebSocket(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	req, _ := NewRequest("echo", WithID(1))
+	if _, err := client.Call(context.Background(), req); err == nil {
+		t.Error("call on closed client succeeded")
+	}
+}
+
+func TestWebSocketBinaryMessage(t *testing.T) {
+	conn := dialTestWebSocket(t, serveWebSocket(t))
+	writeRawFrame(t, conn, true, wsOpBinary, echoRequest(t, 10), true)
+	reply, err := conn.readMessage()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	checkEcho(t, reply, 10)
+}
+
+func TestWebSocketConnectionHandlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []ServerOption
+		wantHandlers int
+	}{
+		{name: "default", wantHandlers: 1},
+		{name: "concurrent", opts: []ServerOption{WithConnectionHandlers(2)}, wantHandlers: 2},
+	}
+	const requests = 6
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu           sync.Mutex
+				active, peak int
+				handled      atomic.Int32
+				release      = make(chan struct{})
+			)
+			s := NewWebSocketServer("", "/", tt.opts...)
+			s.Register("wait", func(ctx context.Context, req *Request) *Response {
+				mu.Lock()
+				active++
+				peak = max(peak, active)
+				mu.Unlock()
+				<-release
+				mu.Lock()
+				active--
+				mu.Unlock()
+				handled.Add(1)
+				return NewResponse(req.ID, WithResult(true))
+			})
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+
+			client, err := DialWebSocket(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http"))
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var wg sync.WaitGroup
+			errs := make(chan error, requests)
+			for i := range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, _ := NewRequest("wait", WithID(i))
+					if _, err := client.Call(ctx, req); err != nil {
+						errs <- err
+					}
+				}()
+			}
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatalf("call failed: %v", err)
+			}
+			if peak > tt.wantHandlers {
+				t.Errorf("got %d messages handled at the same time, want at most %d", peak, tt.wantHandlers)
+			}
+			if got := handled.Load(); got != requests {
+				t.Errorf("got %d messages handled, want %d", got, requests)
+			}
+		})
+	}
+}
+
+func TestWebSocketClientMaxMessageSize(t *testing.T) {
+	client, err := DialWebSocket(context.Background(), serveWebSocket(t), WithClientMaxMessageSize(100))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := NewRequest("echo", WithParams([]string{strings.Repeat("x", 200)}), WithID(1))
+	if _, err := client.Call(ctx, req); err == nil {
+		t.Error("call with a response over the limit succeeded")
+	}
+}
+
+func TestWebSocketCloseWaitsForHandlers(t *testing.T) {
+	// Handlers of a connection closed by the client run to completion with their context intact.
+	done := make(chan error, 1)
+	s := NewWebSocketServer("", "/")
+	s.Register("slow", func(ctx context.Context, req *Request) *Response {
+		time.Sleep(50 * time.Millisecond)
+		done <- ctx.Err()
+		return NewResponse(req.ID, WithResult(true))
+	})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	conn := dialTestWebSocket(t, "ws"+strings.TrimPrefix(ts.URL, "http"))
+	if err := conn.writeMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"slow","id":1}`)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	writeRawFrame(t, conn, true, wsOpClose, binary.BigEndian.AppendUint16(nil, wsCloseNormal), true)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("got handler context error %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not run")
+	}
+}